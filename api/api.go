@@ -0,0 +1,350 @@
+// Package api implements a read-only HTTP API for accessing the metadata registry.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+// EntitiesPath is the path of the API endpoint listing all entities. Metadata of a specific entity
+// is served under EntitiesPath/<public-key>, where the public key is hex or Base64-encoded.
+const EntitiesPath = "/v1/entities"
+
+const (
+	// IndexPath is the path of the API endpoint serving the registry index (see registry.Index).
+	IndexPath = "/" + registry.IndexFilename
+
+	// StatementsPath is the path prefix under which signed statements are served in the same
+	// layout as in the registry repository, as well as under the content-addressed paths listed in
+	// the index (see registry.ContentAddressedPath). Together with IndexPath this allows the API to
+	// be used with registry.NewHTTPProvider.
+	StatementsPath = "/registry/"
+)
+
+// Entity is an entity metadata statement served by the API.
+type Entity struct {
+	// ID is the entity's public key.
+	ID signature.PublicKey `json:"id"`
+
+	// Metadata is the decoded entity metadata.
+	Metadata *registry.EntityMetadata `json:"metadata"`
+
+	// Signed is the signed entity metadata statement as stored in the registry.
+	Signed *signature.Signed `json:"signed"`
+}
+
+// EntitiesResponse is the response of the API endpoint listing all entities.
+type EntitiesResponse struct {
+	// Revision is the registry revision the entities are served from.
+	Revision string `json:"revision,omitempty"`
+
+	// Entities are all entities in the registry, ordered by their public keys. Entities which
+	// have revoked their metadata are skipped.
+	Entities []*Entity `json:"entities"`
+}
+
+// ErrorResponse is the response of the API in case of errors.
+type ErrorResponse struct {
+	// Error is the error message.
+	Error string `json:"error"`
+}
+
+type handler struct {
+	provider registry.StatementProvider
+	logger   *logging.Logger
+
+	// indexed are the encoded statements listed in the two most recently served indexes by their
+	// content-addressed paths, so that clients can fetch the statements of an index even after
+	// the registry has been updated.
+	indexed struct {
+		sync.Mutex
+
+		revision string
+		current  map[string][]byte
+		previous map[string][]byte
+	}
+}
+
+// NewHandler creates a new HTTP handler serving the registry API backed by the given provider.
+//
+// Responses carry an ETag based on the registry revision, so clients can make conditional
+// requests to avoid transferring unchanged metadata. In case the provider implements
+// registry.SnapshotProvider, each request is served from a single snapshot of the registry, so the
+// ETag always matches the served metadata.
+//
+// The index lists statements under their content-addressed paths. Statements listed in the two most
+// recently served indexes remain available under these paths after the registry has been updated,
+// so clients fetching the statements of an index are not affected by concurrent updates.
+func NewHandler(p registry.StatementProvider) http.Handler {
+	h := &handler{
+		provider: p,
+		logger:   logging.GetLogger("registry/api"),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(EntitiesPath, h.handleEntities)
+	mux.HandleFunc(EntitiesPath+"/", h.handleEntity)
+	mux.HandleFunc(IndexPath, h.handleIndex)
+	mux.HandleFunc(StatementsPath, h.handleStatement)
+	return mux
+}
+
+func (h *handler) handleEntities(w http.ResponseWriter, r *http.Request) {
+	snapshot, revision, ok := h.checkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	stmts, err := snapshot.GetSignedStatements(r.Context(), registry.EntityStatementKind)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := EntitiesResponse{
+		Revision: revision,
+		Entities: make([]*Entity, 0, len(stmts)),
+	}
+	for id, stmt := range stmts {
+		meta := stmt.Statement.(*registry.EntityMetadata)
+		if meta.Revoked {
+			// Skip entities which have revoked their metadata.
+			continue
+		}
+		rsp.Entities = append(rsp.Entities, &Entity{
+			ID:       id.(signature.PublicKey),
+			Metadata: meta,
+			Signed:   stmt.Signed,
+		})
+	}
+	sort.Slice(rsp.Entities, func(i, j int) bool {
+		return bytes.Compare(rsp.Entities[i].ID[:], rsp.Entities[j].ID[:]) < 0
+	})
+
+	h.writeJSON(w, http.StatusOK, &rsp)
+}
+
+func (h *handler) handleEntity(w http.ResponseWriter, r *http.Request) {
+	snapshot, _, ok := h.checkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := registry.ParsePublicKey(strings.TrimPrefix(r.URL.Path, EntitiesPath+"/"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	stmt, err := snapshot.GetSignedStatement(r.Context(), registry.EntityStatementKind, id)
+	switch {
+	case err == nil:
+	case errors.Is(err, registry.ErrNoSuchEntity):
+		h.writeError(w, http.StatusNotFound, err)
+		return
+	default:
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Revocation tombstones are served together with the Gone status.
+	status := http.StatusOK
+	meta := stmt.Statement.(*registry.EntityMetadata)
+	if meta.Revoked {
+		status = http.StatusGone
+	}
+
+	h.writeJSON(w, status, &Entity{
+		ID:       id,
+		Metadata: meta,
+		Signed:   stmt.Signed,
+	})
+}
+
+func (h *handler) handleIndex(w http.ResponseWriter, r *http.Request) {
+	snapshot, _, ok := h.checkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	stmts := make(map[string][]byte)
+	index, err := registry.NewContentAddressedIndex(r.Context(), snapshot, func(path string, data []byte) error {
+		stmts[path] = data
+		return nil
+	})
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.addIndexed(index.Revision, stmts)
+
+	h.writeJSON(w, http.StatusOK, index)
+}
+
+// addIndexed records the statements listed in the index of the given revision.
+func (h *handler) addIndexed(revision string, stmts map[string][]byte) {
+	h.indexed.Lock()
+	defer h.indexed.Unlock()
+
+	if revision != h.indexed.revision || revision == "" {
+		h.indexed.previous = h.indexed.current
+	}
+	h.indexed.revision = revision
+	h.indexed.current = stmts
+}
+
+// getIndexed returns the statement stored at the given content-addressed path in case it is listed
+// in one of the most recently served indexes.
+func (h *handler) getIndexed(path string) ([]byte, bool) {
+	h.indexed.Lock()
+	defer h.indexed.Unlock()
+
+	if data, ok := h.indexed.current[path]; ok {
+		return data, true
+	}
+	data, ok := h.indexed.previous[path]
+	return data, ok
+}
+
+func (h *handler) handleStatement(w http.ResponseWriter, r *http.Request) {
+	stmtPath := strings.TrimPrefix(r.URL.Path, "/")
+
+	// Content-addressed statements never change, so recently indexed ones are served regardless
+	// of the current registry revision.
+	if data, ok := h.getIndexed(stmtPath); ok {
+		if !h.checkMethod(w, r) {
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		h.writeStatement(w, data)
+		return
+	}
+
+	snapshot, _, ok := h.checkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	kind, id, err := registry.ParseStatementPath(stmtPath)
+	contentAddressed := err != nil
+	if contentAddressed {
+		kind, id, err = registry.ParseContentAddressedPath(stmtPath)
+	}
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	stmt, err := snapshot.GetSignedStatement(r.Context(), kind, id)
+	switch {
+	case err == nil:
+	case errors.Is(err, kind.ErrNoSuchStatement):
+		h.writeError(w, http.StatusNotFound, err)
+		return
+	default:
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Statements are served in their canonical encoding, so they match the index.
+	data, err := json.Marshal(stmt.Signed)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if contentAddressed && registry.ContentAddressedPath(kind, id, data) != stmtPath {
+		h.writeError(w, http.StatusNotFound, kind.ErrNoSuchStatement)
+		return
+	}
+	h.writeStatement(w, data)
+}
+
+func (h *handler) writeStatement(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		h.logger.Error("failed to write response",
+			"err", err,
+		)
+	}
+}
+
+// checkMethod checks that the request method is allowed. In case it is not, the response is
+// written and false is returned.
+func (h *handler) checkMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		h.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return false
+	}
+	return true
+}
+
+// checkRequest checks the request method, takes a snapshot of the registry to serve the request
+// from and sets the ETag header based on the revision of the snapshot. In case the request cannot
+// or need not be served (e.g. because the client already has the current revision), the response
+// is written and false is returned.
+func (h *handler) checkRequest(w http.ResponseWriter, r *http.Request) (registry.StatementProvider, string, bool) {
+	if !h.checkMethod(w, r) {
+		return nil, "", false
+	}
+
+	snapshot, err := registry.Snapshot(r.Context(), h.provider)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return nil, "", false
+	}
+
+	revision := snapshot.Revision()
+	if revision == "" {
+		return snapshot, revision, true
+	}
+
+	etag := `"` + revision + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil, revision, false
+	}
+	return snapshot, revision, true
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error("failed to write response",
+			"err", err,
+		)
+	}
+}
+
+func (h *handler) writeError(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusInternalServerError {
+		h.logger.Error("failed to serve request",
+			"err", err,
+		)
+	}
+	h.writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+}
+
+// etagMatches checks whether the If-None-Match header value matches the given ETag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+func get(require *require.Assertions, srv *httptest.Server, path, etag string, v interface{}) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	require.NoError(err, "NewRequest")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rsp, err := srv.Client().Do(req)
+	require.NoError(err, "Do")
+	defer rsp.Body.Close()
+
+	if v != nil && rsp.StatusCode != http.StatusNotModified {
+		require.NoError(json.NewDecoder(rsp.Body).Decode(v), "Decode")
+	}
+	return rsp
+}
+
+func updateEntity(
+	require *require.Assertions,
+	fp registry.MutableProvider,
+	signer signature.Signer,
+	meta *registry.EntityMetadata,
+) {
+	signed, err := registry.SignEntityMetadata(signer, meta)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+}
+
+func TestHandler(t *testing.T) {
+	require := require.New(t)
+
+	fp, err := registry.NewFilesystemProviderWithConfig(memfs.New(), registry.FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	srv := httptest.NewServer(NewHandler(fp))
+	defer srv.Close()
+
+	// Empty registry.
+	var entities EntitiesResponse
+	rsp := get(require, srv, EntitiesPath, "", &entities)
+	require.Equal(http.StatusOK, rsp.StatusCode)
+	require.Equal("application/json", rsp.Header.Get("Content-Type"))
+	require.Equal(fp.Revision(), entities.Revision)
+	require.NotNil(entities.Entities)
+	require.Empty(entities.Entities)
+	emptyETag := rsp.Header.Get("ETag")
+	require.Equal(`"`+fp.Revision()+`"`, emptyETag)
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	meta := &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "hello world",
+	}
+	updateEntity(require, fp, signer, meta)
+	updateEntity(require, fp, other, &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "other world",
+	})
+
+	// The ETag changes with the registry revision.
+	rsp = get(require, srv, EntitiesPath, emptyETag, &entities)
+	require.Equal(http.StatusOK, rsp.StatusCode)
+	etag := rsp.Header.Get("ETag")
+	require.NotEqual(emptyETag, etag)
+	require.Len(entities.Entities, 2)
+	for _, entity := range entities.Entities {
+		var opened registry.EntityMetadata
+		require.NoError(entity.Signed.Open(registry.EntityMetadataSignatureContext, &opened), "Open")
+		require.EqualValues(entity.Metadata, &opened)
+		require.Equal(entity.ID, entity.Signed.Signature.PublicKey)
+	}
+
+	rsp = get(require, srv, EntitiesPath, etag, nil)
+	require.Equal(http.StatusNotModified, rsp.StatusCode)
+	rsp = get(require, srv, EntitiesPath+"/"+signer.Public().String(), "W/"+etag, nil)
+	require.Equal(http.StatusNotModified, rsp.StatusCode)
+
+	// Entities can be looked up by hex or Base64-encoded public keys.
+	pk := signer.Public()
+	for _, id := range []string{
+		hex.EncodeToString(pk[:]),
+		url.PathEscape(pk.String()),
+		pk.String(),
+	} {
+		var entity Entity
+		rsp = get(require, srv, EntitiesPath+"/"+id, "", &entity)
+		require.Equal(http.StatusOK, rsp.StatusCode, id)
+		require.Equal(signer.Public(), entity.ID)
+		require.EqualValues(meta, entity.Metadata)
+		require.Equal(signer.Public(), entity.Signed.Signature.PublicKey)
+	}
+
+	var errRsp ErrorResponse
+	missing := memorySigner.NewTestSigner("metadata-registry-tools missing test entity signer")
+	rsp = get(require, srv, EntitiesPath+"/"+missing.Public().String(), "", &errRsp)
+	require.Equal(http.StatusNotFound, rsp.StatusCode)
+	require.Equal(registry.ErrNoSuchEntity.Error(), errRsp.Error)
+	rsp = get(require, srv, EntitiesPath+"/bad", "", &errRsp)
+	require.Equal(http.StatusBadRequest, rsp.StatusCode)
+
+	// Revoked entities are skipped when listing and served as gone.
+	updateEntity(require, fp, signer, &registry.EntityMetadata{
+		Versioned:        cbor.NewVersioned(registry.MaxSupportedVersion),
+		Serial:           2,
+		Revoked:          true,
+		RevocationReason: "bye",
+	})
+	get(require, srv, EntitiesPath, "", &entities)
+	require.Len(entities.Entities, 1)
+	require.Equal(other.Public(), entities.Entities[0].ID)
+	var revoked Entity
+	rsp = get(require, srv, EntitiesPath+"/"+signer.Public().String(), "", &revoked)
+	require.Equal(http.StatusGone, rsp.StatusCode)
+	require.True(revoked.Metadata.Revoked)
+
+	// The API can be used as a registry mirror.
+	hp, err := registry.NewHTTPProvider(srv.URL)
+	require.NoError(err, "NewHTTPProvider")
+	require.NoError(hp.Verify(), "Verify")
+	require.Equal(fp.Revision(), hp.Revision())
+	mirrored, err := hp.GetEntities(context.Background())
+	require.NoError(err, "GetEntities")
+	require.Len(mirrored, 1)
+	require.EqualValues(entities.Entities[0].Metadata, mirrored[other.Public()])
+	_, err = hp.GetEntity(context.Background(), signer.Public())
+	require.True(errors.Is(err, registry.ErrEntityRevoked), "GetEntity should fail for revoked entities")
+	rsp = get(require, srv, StatementsPath+"entity/bad.json", "", &errRsp)
+	require.Equal(http.StatusNotFound, rsp.StatusCode)
+
+	// Statements of a fetched index remain available after the registry has been updated.
+	snapshot, err := hp.(registry.SnapshotProvider).Snapshot(context.Background())
+	require.NoError(err, "Snapshot")
+	var index registry.Index
+	get(require, srv, IndexPath, "", &index)
+	entry := index.Statements[registry.EntityStatementKind.Name][0]
+	require.Equal("registry/entity/"+entry.ID+"/"+entry.SHA256+".json", entry.Path)
+	updateEntity(require, fp, other, &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    2,
+		Name:      "other updated world",
+	})
+	var stale registry.EntityMetadata
+	var signed signature.Signed
+	rsp = get(require, srv, "/"+entry.Path, "", &signed)
+	require.Equal(http.StatusOK, rsp.StatusCode)
+	require.NoError(signed.Open(registry.EntityMetadataSignatureContext, &stale), "Open")
+	require.EqualValues(1, stale.Serial)
+	mirrored, err = snapshot.GetEntities(context.Background())
+	require.NoError(err, "GetEntities should not be affected by updates")
+	require.EqualValues(1, mirrored[other.Public()].Serial)
+	mirrored, err = hp.GetEntities(context.Background())
+	require.NoError(err, "GetEntities")
+	require.EqualValues(2, mirrored[other.Public()].Serial)
+	rsp = get(require, srv, StatementsPath+"entity/"+entry.ID+"/"+strings.Repeat("0", 64)+".json", "", &errRsp)
+	require.Equal(http.StatusNotFound, rsp.StatusCode)
+
+	// Only reading is allowed.
+	rsp, err = srv.Client().Post(srv.URL+EntitiesPath, "application/json", strings.NewReader("{}"))
+	require.NoError(err, "Post")
+	rsp.Body.Close()
+	require.Equal(http.StatusMethodNotAllowed, rsp.StatusCode)
+}
+
+// snapshotProvider is a provider whose snapshots serve a different registry than the provider
+// itself, as if the registry had been updated right after the snapshot was taken.
+type snapshotProvider struct {
+	registry.StatementProvider
+
+	snapshot registry.StatementProvider
+}
+
+// Implements registry.SnapshotProvider.
+func (p *snapshotProvider) Snapshot(context.Context) (registry.StatementProvider, error) {
+	return p.snapshot, nil
+}
+
+func TestHandlerSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	newProvider := func() registry.MutableStatementProvider {
+		fp, err := registry.NewFilesystemProviderWithConfig(memfs.New(), registry.FilesystemConfig{})
+		require.NoError(err, "NewFilesystemProviderWithConfig")
+		require.NoError(fp.Init(), "Init")
+		return fp
+	}
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	snapshot := newProvider()
+	updateEntity(require, snapshot, signer, &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+	})
+	updated := newProvider()
+	updateEntity(require, updated, signer, &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    2,
+	})
+
+	// The ETag and the content of each response are taken from the same snapshot.
+	cp := registry.NewCachingProvider(snapshot, registry.CacheOptions{})
+	srv := httptest.NewServer(NewHandler(&snapshotProvider{
+		StatementProvider: updated,
+		snapshot:          cp,
+	}))
+	defer srv.Close()
+
+	var entities EntitiesResponse
+	rsp := get(require, srv, EntitiesPath, "", &entities)
+	require.Equal(http.StatusOK, rsp.StatusCode)
+	require.Equal(`"`+snapshot.Revision()+`"`, rsp.Header.Get("ETag"))
+	require.Equal(snapshot.Revision(), entities.Revision)
+	require.Len(entities.Entities, 1)
+	require.EqualValues(1, entities.Entities[0].Metadata.Serial)
+
+	var entity Entity
+	rsp = get(require, srv, EntitiesPath+"/"+signer.Public().String(), "", &entity)
+	require.Equal(http.StatusOK, rsp.StatusCode)
+	require.Equal(`"`+snapshot.Revision()+`"`, rsp.Header.Get("ETag"))
+	require.EqualValues(1, entity.Metadata.Serial)
+
+	var opened registry.EntityMetadata
+	require.NoError(entity.Signed.Open(registry.EntityMetadataSignatureContext, &opened), "Open")
+	require.EqualValues(entity.Metadata, &opened)
+
+	// Each statement is only loaded once per request.
+	require.Equal(registry.CacheStats{Misses: 1, Hits: 1}, cp.Stats())
+}
@@ -0,0 +1,428 @@
+package registry
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// CacheOptions contains the options of the caching provider.
+type CacheOptions struct {
+	// MaxEntries is the maximum number of cached statements. Once reached, the least recently used
+	// statement is evicted to make room for a new one. Zero means no limit.
+	MaxEntries int
+
+	// Workers is the maximum number of statements verified in parallel on cache misses. If zero,
+	// the number of CPUs usable by the process (GOMAXPROCS) is used.
+	Workers int
+
+	// RevisionInterval is the minimum interval between recomputing the revision of the wrapped
+	// provider. Until then, the previously computed revision is returned even if the registry has
+	// been updated, so it should only be set for providers whose revision is expensive to compute
+	// (e.g. a filesystem provider not backed by a local directory hashes all statements). Zero
+	// means that the revision is always recomputed.
+	RevisionInterval time.Duration
+}
+
+// CacheStats contains the statistics of the caching provider.
+type CacheStats struct {
+	// Hits is the number of statements served from the cache.
+	Hits uint64
+
+	// Misses is the number of statements which had to be loaded and verified.
+	Misses uint64
+}
+
+// CachingProvider is a registry provider which caches verified statements.
+type CachingProvider interface {
+	StatementProvider
+
+	// Stats returns the cache statistics.
+	Stats() CacheStats
+
+	// Purge removes all statements from the cache.
+	Purge()
+}
+
+// NewCachingProvider creates a new registry provider which caches statements verified by the
+// given provider, so they are not loaded and verified again on each request.
+//
+// In case the given provider is also a StatementSource (as are all providers in this package),
+// verified statements are cached by the hash of their content, so any changed statement (e.g.
+// after a file is modified or a Git provider is refreshed) is verified again. Otherwise, the
+// statements are cached until the registry revision changes and nothing is cached in case the
+// revision cannot be determined.
+//
+// Statements returned by the caching provider are shared between callers and must not be
+// modified.
+func NewCachingProvider(p StatementProvider, opts CacheOptions) CachingProvider {
+	if src, ok := p.(StatementSource); ok {
+		return &sourceProvider{
+			src:      src,
+			cache:    newStatementCache(opts),
+			revision: &cachedRevision{interval: opts.RevisionInterval},
+			workers:  opts.Workers,
+		}
+	}
+	return &revisionCachingProvider{
+		StatementProvider: p,
+		revision:          &cachedRevision{interval: opts.RevisionInterval},
+		listings:          make(map[*StatementKind]*cachedListing),
+	}
+}
+
+// cachedRevision is the registry revision of a provider, recomputed at most once per interval.
+type cachedRevision struct {
+	sync.Mutex
+
+	interval time.Duration
+	revision string
+	updated  time.Time
+}
+
+// get returns the cached revision, using fn to recompute it once the interval has elapsed.
+func (c *cachedRevision) get(fn func() string) string {
+	if c == nil || c.interval <= 0 {
+		return fn()
+	}
+
+	// Concurrent callers wait for the revision to be computed only once.
+	c.Lock()
+	defer c.Unlock()
+	if c.updated.IsZero() || time.Since(c.updated) >= c.interval {
+		c.revision = fn()
+		c.updated = time.Now()
+	}
+	return c.revision
+}
+
+func (c *cachedRevision) purge() {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.updated = time.Time{}
+}
+
+type statementCacheKey struct {
+	kind *StatementKind
+	id   StatementID
+}
+
+type statementCacheEntry struct {
+	key    statementCacheKey
+	hash   [sha256.Size]byte
+	stmt   Statement
+	signed *signature.Signed
+}
+
+// statementCache is a cache of verified statements, keyed by the hash of their content. In case
+// the number of entries is limited, the least recently used entries are evicted.
+type statementCache struct {
+	sync.Mutex
+
+	maxEntries int
+	entries    map[statementCacheKey]*list.Element
+	lru        *list.List
+	hits       uint64
+	misses     uint64
+}
+
+// load verifies the given encoded signed statement of the given kind, unless an identical
+// statement has already been verified.
+func (c *statementCache) load(kind *StatementKind, id StatementID, data []byte) (Statement, *signature.Signed, error) {
+	key := statementCacheKey{kind, id}
+	hash := sha256.Sum256(data)
+
+	c.Lock()
+	if elem := c.entries[key]; elem != nil {
+		if entry := elem.Value.(*statementCacheEntry); entry.hash == hash {
+			c.hits++
+			c.lru.MoveToFront(elem)
+			c.Unlock()
+			return entry.stmt, entry.signed, nil
+		}
+	}
+	c.misses++
+	c.Unlock()
+
+	// Verify the statement without holding the lock, failures are not cached.
+	stmt := kind.New()
+	signed, err := kind.loadSigned(id, bytes.NewReader(data), stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	entry := &statementCacheEntry{
+		key:    key,
+		hash:   hash,
+		stmt:   stmt,
+		signed: signed,
+	}
+	if elem := c.entries[key]; elem != nil {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return stmt, signed, nil
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+	return stmt, signed, nil
+}
+
+// remove removes the given entry from the cache.
+func (c *statementCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*statementCacheEntry).key)
+	c.lru.Remove(elem)
+}
+
+// retain removes all cached statements of the given kind, except for the given ones.
+func (c *statementCache) retain(kind *StatementKind, stmts map[StatementID]*SignedStatement) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, elem := range c.entries {
+		if _, ok := stmts[key.id]; key.kind == kind && !ok {
+			c.remove(elem)
+		}
+	}
+}
+
+func (c *statementCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return CacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+	}
+}
+
+func (c *statementCache) purge() {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.entries = make(map[statementCacheKey]*list.Element)
+	c.lru.Init()
+}
+
+func newStatementCache(opts CacheOptions) *statementCache {
+	return &statementCache{
+		maxEntries: opts.MaxEntries,
+		entries:    make(map[statementCacheKey]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// cachedListing contains all statements of a kind as of a specific registry revision.
+type cachedListing struct {
+	revision string
+	stmts    map[StatementID]*SignedStatement
+}
+
+// revisionCachingProvider is a caching provider for providers which are not statement sources,
+// caching all statements of a kind until the registry revision changes.
+type revisionCachingProvider struct {
+	StatementProvider
+
+	revision *cachedRevision
+
+	sync.Mutex
+	listings map[*StatementKind]*cachedListing
+	hits     uint64
+	misses   uint64
+}
+
+// cached returns the cached statements of the given kind as of the current registry revision
+// (if any), together with the current revision.
+func (p *revisionCachingProvider) cached(kind *StatementKind) (map[StatementID]*SignedStatement, string) {
+	revision := p.Revision()
+
+	p.Lock()
+	defer p.Unlock()
+
+	listing := p.listings[kind]
+	if revision == "" || listing == nil || listing.revision != revision {
+		return nil, revision
+	}
+	return listing.stmts, revision
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) Revision() string {
+	return p.revision.get(p.StatementProvider.Revision)
+}
+
+// Implements Provider.
+func (p *revisionCachingProvider) Verify() error {
+	return p.VerifyContext(context.Background())
+}
+
+// Implements Provider.
+func (p *revisionCachingProvider) VerifyUpdate(src Provider) error {
+	return p.VerifyUpdateContext(context.Background(), src)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) VerifyContext(ctx context.Context) error {
+	return verifyStatements(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) VerifyUpdateContext(ctx context.Context, src Provider) error {
+	return verifyStatementsUpdates(ctx, p, src, nil)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetStatements(
+	ctx context.Context,
+	kind *StatementKind,
+) (map[StatementID]Statement, error) {
+	signed, err := p.GetSignedStatements(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	return unsignedStatements(signed), nil
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetSignedStatements(
+	ctx context.Context,
+	kind *StatementKind,
+) (map[StatementID]*SignedStatement, error) {
+	stmts, revision := p.cached(kind)
+	if stmts == nil {
+		var err error
+		if stmts, err = p.StatementProvider.GetSignedStatements(ctx, kind); err != nil {
+			return nil, err
+		}
+
+		p.Lock()
+		p.misses += uint64(len(stmts))
+		if revision != "" {
+			p.listings[kind] = &cachedListing{
+				revision: revision,
+				stmts:    stmts,
+			}
+		}
+		p.Unlock()
+	} else {
+		p.Lock()
+		p.hits += uint64(len(stmts))
+		p.Unlock()
+	}
+
+	results := make(map[StatementID]*SignedStatement, len(stmts))
+	for id, stmt := range stmts {
+		results[id] = stmt
+	}
+	return results, nil
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetStatement(
+	ctx context.Context,
+	kind *StatementKind,
+	id StatementID,
+) (Statement, error) {
+	signed, err := p.GetSignedStatement(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	return signed.Statement, nil
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetSignedStatement(
+	ctx context.Context,
+	kind *StatementKind,
+	id StatementID,
+) (*SignedStatement, error) {
+	stmts, _ := p.cached(kind)
+	if stmts == nil {
+		p.Lock()
+		p.misses++
+		p.Unlock()
+		return p.StatementProvider.GetSignedStatement(ctx, kind, id)
+	}
+
+	p.Lock()
+	p.hits++
+	p.Unlock()
+	stmt, ok := stmts[id]
+	if !ok {
+		return nil, kind.ErrNoSuchStatement
+	}
+	return stmt, nil
+}
+
+// Implements Provider.
+func (p *revisionCachingProvider) GetEntities(ctx context.Context) (map[signature.PublicKey]*EntityMetadata, error) {
+	return getEntities(ctx, p)
+}
+
+// Implements Provider.
+func (p *revisionCachingProvider) GetEntity(ctx context.Context, id signature.PublicKey) (*EntityMetadata, error) {
+	return getEntity(ctx, p, id)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetNodes(ctx context.Context) (map[signature.PublicKey]*NodeMetadata, error) {
+	return getNodes(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetNode(ctx context.Context, id signature.PublicKey) (*NodeMetadata, error) {
+	return getNode(ctx, p, id)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetRuntimes(ctx context.Context) (map[common.Namespace]*RuntimeMetadata, error) {
+	return getRuntimes(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *revisionCachingProvider) GetRuntime(ctx context.Context, id common.Namespace) (*RuntimeMetadata, error) {
+	return getRuntime(ctx, p, id)
+}
+
+// Implements CachingProvider.
+func (p *revisionCachingProvider) Stats() CacheStats {
+	p.Lock()
+	defer p.Unlock()
+
+	return CacheStats{
+		Hits:   p.hits,
+		Misses: p.misses,
+	}
+}
+
+// Implements CachingProvider.
+func (p *revisionCachingProvider) Purge() {
+	p.revision.purge()
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.listings = make(map[*StatementKind]*cachedListing)
+}
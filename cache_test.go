@@ -0,0 +1,193 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// uncachedProvider hides all but the StatementProvider methods of the wrapped provider.
+type uncachedProvider struct {
+	StatementProvider
+}
+
+func TestCachingProvider(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fs := memfs.New()
+	fp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	signers := newTestEntities(require, fp, 3)
+
+	cp := NewCachingProvider(fp, CacheOptions{})
+	entities, err := cp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 3)
+	require.Equal(CacheStats{Hits: 0, Misses: 3}, cp.Stats())
+
+	entities, err = cp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 3)
+	require.Equal(CacheStats{Hits: 3, Misses: 3}, cp.Stats())
+	entity, err := cp.GetEntity(ctx, signers[0].Public())
+	require.NoError(err, "GetEntity")
+	require.Equal("entity 0", entity.Name)
+	require.Equal(CacheStats{Hits: 4, Misses: 3}, cp.Stats())
+	require.Equal(fp.Revision(), cp.Revision())
+
+	// Updated statements are verified again.
+	signed, err := SignEntityMetadata(signers[0], &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    2,
+		Name:      "updated entity 0",
+	})
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	entities, err = cp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Equal("updated entity 0", entities[signers[0].Public()].Name)
+	require.Equal(CacheStats{Hits: 6, Misses: 4}, cp.Stats())
+
+	// Modified files are not served from the cache.
+	path := EntityStatementKind.Path(signers[1].Public())
+	data, err := util.ReadFile(fs, path)
+	require.NoError(err, "ReadFile")
+	require.NoError(util.WriteFile(fs, path, data[:len(data)-1], 0o644), "WriteFile")
+	_, err = cp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntities should fail for modified statements")
+	_, err = cp.GetEntity(ctx, signers[1].Public())
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntity should fail for modified statements")
+	require.NoError(util.WriteFile(fs, path, data, 0o644), "WriteFile")
+
+	cp.Purge()
+	before := cp.Stats()
+	_, err = cp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Equal(before.Misses+3, cp.Stats().Misses)
+
+	// The number of cached statements can be limited.
+	cp = NewCachingProvider(fp, CacheOptions{MaxEntries: 1})
+	for i := 0; i < 2; i++ {
+		_, err = cp.GetEntities(ctx)
+		require.NoError(err, "GetEntities")
+	}
+	require.LessOrEqual(cp.Stats().Hits, uint64(1))
+
+	// The least recently used statements are evicted.
+	cp = NewCachingProvider(fp, CacheOptions{MaxEntries: 2})
+	for _, i := range []int{0, 1, 0, 2, 0, 1} {
+		_, err = cp.GetEntity(ctx, signers[i].Public())
+		require.NoError(err, "GetEntity")
+	}
+	require.Equal(CacheStats{Hits: 2, Misses: 4}, cp.Stats())
+
+	// Providers which are not statement sources are cached by revision.
+	cp = NewCachingProvider(&uncachedProvider{fp}, CacheOptions{})
+	for i := 0; i < 2; i++ {
+		entities, err = cp.GetEntities(ctx)
+		require.NoError(err, "GetEntities")
+		require.Len(entities, 3)
+	}
+	require.Equal(CacheStats{Hits: 3, Misses: 3}, cp.Stats())
+	_, err = cp.GetEntity(ctx, signers[2].Public())
+	require.NoError(err, "GetEntity")
+	missing := memorySigner.NewTestSigner("metadata-registry-tools missing test entity signer")
+	_, err = cp.GetEntity(ctx, missing.Public())
+	require.True(errors.Is(err, ErrNoSuchEntity), "GetEntity should fail for missing entities")
+	require.NoError(cp.Verify(), "Verify")
+
+	signed, err = SignEntityMetadata(signers[2], &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    2,
+		Name:      "updated entity 2",
+	})
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	before = cp.Stats()
+	entities, err = cp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Equal("updated entity 2", entities[signers[2].Public()].Name)
+	require.Equal(before.Misses+3, cp.Stats().Misses)
+}
+
+func BenchmarkGetEntities(b *testing.B) {
+	require := require.New(b)
+	ctx := context.Background()
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	newTestEntities(require, fp, 10_000)
+
+	for _, tc := range []struct {
+		name string
+		p    Provider
+	}{
+		{"Uncached", fp},
+		{"Cached", NewCachingProvider(fp, CacheOptions{})},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			// Warm up the cache (if any).
+			_, err := tc.p.GetEntities(ctx)
+			require.NoError(err, "GetEntities")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = tc.p.GetEntities(ctx); err != nil {
+					b.Fatalf("GetEntities: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCachingProviderRevision(t *testing.T) {
+	require := require.New(t)
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	signers := newTestEntities(require, fp, 1)
+	serial := uint64(1)
+	update := func() {
+		serial++
+		signed, serr := SignEntityMetadata(signers[0], &EntityMetadata{
+			Versioned: cbor.NewVersioned(1),
+			Serial:    serial,
+		})
+		require.NoError(serr, "SignEntityMetadata")
+		require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	}
+
+	for _, tc := range []struct {
+		name string
+		p    StatementProvider
+	}{
+		{"Source", fp},
+		{"Revision", &uncachedProvider{fp}},
+	} {
+		// The revision is not recomputed until the interval elapses or the cache is purged.
+		cp := NewCachingProvider(tc.p, CacheOptions{RevisionInterval: time.Hour})
+		revision := cp.Revision()
+		require.Equal(fp.Revision(), revision, tc.name)
+		update()
+		require.Equal(revision, cp.Revision(), tc.name)
+		cp.Purge()
+		require.Equal(fp.Revision(), cp.Revision(), tc.name)
+
+		cp = NewCachingProvider(tc.p, CacheOptions{RevisionInterval: time.Nanosecond})
+		revision = cp.Revision()
+		update()
+		time.Sleep(time.Millisecond)
+		require.NotEqual(revision, cp.Revision(), tc.name)
+	}
+}
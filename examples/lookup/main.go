@@ -11,7 +11,8 @@ import (
 
 func main() {
 	// Create a new instance of the registry client, pointing to the production instance.
-	// Note that in order to refresh the data you currently need to create a new provider.
+	// Note that in order to refresh the data you need to call Refresh on the provider (or set
+	// GitConfig.RefreshInterval to refresh it in the background).
 	gp, err := registry.NewGitProvider(registry.NewGitConfig())
 	if err != nil {
 		fmt.Printf("Failed to create Git registry provider: %s\n", err)
@@ -0,0 +1,190 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// ExportStatic exports all statements served by the given provider as a static mirror of the
+// registry, which can be accessed with NewHTTPProvider.
+//
+// Every signed statement is written in its canonical encoding to a content-addressed path (see
+// ContentAddressedPath) referenced by the registry index, as well as to the same path as in the
+// registry repository for direct lookups. The index is written to IndexFilename last, so clients
+// never see an index referencing statements which have not been written yet, and statements
+// referenced by an index are never modified, so clients which fetched the previous index can
+// still fetch matching statements while the mirror is being updated.
+//
+// Once the new index has been written, statements referenced by neither the new nor the previous
+// index (e.g. left over from earlier exports) are removed.
+func ExportStatic(ctx context.Context, p StatementProvider, fs billy.Filesystem) (*Index, error) {
+	previous, err := readStaticIndex(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := newIndex(ctx, p, func(kind *StatementKind, id StatementID, data []byte) (string, error) {
+		stmtPath := ContentAddressedPath(kind, id, data)
+		if err := writeStaticFile(fs, stmtPath, data); err != nil {
+			return "", err
+		}
+		if err := writeStaticFile(fs, kind.Path(id), data); err != nil {
+			return "", err
+		}
+		return stmtPath, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index: %w", err)
+	}
+	if err = writeFileAtomic(fs, IndexFilename, data); err != nil {
+		return nil, err
+	}
+	if err = pruneStatic(fs, index, previous); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// readStaticIndex reads the index of a previously exported static mirror. In case there is no
+// valid index, nil is returned.
+func readStaticIndex(fs billy.Filesystem) (*Index, error) {
+	data, err := util.ReadFile(fs, IndexFilename)
+	switch {
+	case err == nil:
+	case os.IsNotExist(err):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to read %s: %w", IndexFilename, err)
+	}
+
+	var index Index
+	if err = json.Unmarshal(data, &index); err != nil || index.Verify() != nil {
+		return nil, nil
+	}
+	return &index, nil
+}
+
+// pruneStatic removes all statements from a static mirror which are not in the given index, except
+// for the content-addressed statements referenced by the previous index (if any).
+func pruneStatic(fs billy.Filesystem, index, previous *Index) error {
+	for _, kind := range StatementKinds() {
+		// Statements in the index are stored at both paths.
+		indexed := make(map[string]bool)
+		for _, entry := range index.Statements[kind.Name] {
+			indexed[path.Join(registryDir, kind.Dir, entry.ID+statementExt)] = true
+			indexed[entry.Path] = true
+		}
+		if previous != nil {
+			for _, entry := range previous.Statements[kind.Name] {
+				indexed[entry.Path] = true
+			}
+		}
+
+		dir := path.Join(registryDir, kind.Dir)
+		files, err := fs.ReadDir(dir)
+		switch {
+		case err == nil:
+		case os.IsNotExist(err):
+			continue
+		default:
+			return fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, fi := range files {
+			var stale []string
+			switch {
+			case fi.IsDir():
+				// Content-addressed statements.
+				if stale, err = staleStaticStatements(fs, path.Join(dir, fi.Name()), indexed); err != nil {
+					return err
+				}
+			case filepath.Ext(fi.Name()) == statementExt && !indexed[path.Join(dir, fi.Name())]:
+				stale = []string{path.Join(dir, fi.Name())}
+			}
+
+			for _, stalePath := range stale {
+				if err = fs.Remove(stalePath); err != nil {
+					return fmt.Errorf("failed to remove stale %s statement %s: %w", kind.Name, stalePath, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// staleStaticStatements returns the paths of all content-addressed statements in the given
+// directory which are not indexed. In case nothing else remains, the directory itself is returned
+// last, so it is removed once it is empty.
+func staleStaticStatements(fs billy.Filesystem, dir string, indexed map[string]bool) ([]string, error) {
+	files, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var (
+		stale []string
+		keep  bool
+	)
+	for _, fi := range files {
+		stmtPath := path.Join(dir, fi.Name())
+		switch {
+		case fi.IsDir() || filepath.Ext(fi.Name()) != statementExt:
+			// Not a statement, so the directory must be kept.
+			keep = true
+		case indexed[stmtPath]:
+			keep = true
+		default:
+			stale = append(stale, stmtPath)
+		}
+	}
+	if !keep {
+		stale = append(stale, dir)
+	}
+	return stale, nil
+}
+
+// writeStaticFile writes the file atomically (see writeFileAtomic), unless it already has the given
+// content.
+func writeStaticFile(fs billy.Filesystem, filename string, data []byte) error {
+	if existing, err := util.ReadFile(fs, filename); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return writeFileAtomic(fs, filename, data)
+}
+
+// ExportStaticPath exports all statements served by the given provider as a static mirror of the
+// registry to the given path (see ExportStatic).
+func ExportStaticPath(ctx context.Context, p StatementProvider, dir string) (*Index, error) {
+	return ExportStatic(ctx, p, osfs.New(dir))
+}
+
+// writeFileAtomic writes the file by renaming a temporary file, so that readers never observe a
+// partially written file.
+func writeFileAtomic(fs billy.Filesystem, filename string, data []byte) error {
+	if err := fs.MkdirAll(path.Dir(filename), 0o755); err != nil {
+		return fmt.Errorf("failed to create path %s: %w", path.Dir(filename), err)
+	}
+
+	tmpFilename := filename + ".tmp"
+	if err := util.WriteFile(fs, tmpFilename, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := fs.Rename(tmpFilename, filename); err != nil {
+		_ = fs.Remove(tmpFilename)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
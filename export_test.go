@@ -0,0 +1,179 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportStatic(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	for _, entity := range []struct {
+		signer signature.Signer
+		meta   *EntityMetadata
+	}{
+		{signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "hello world"}},
+		{other, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "other world"}},
+		{signer, &EntityMetadata{Versioned: cbor.NewVersioned(MaxSupportedVersion), Serial: 2, Revoked: true}},
+	} {
+		signed, serr := SignEntityMetadata(entity.signer, entity.meta)
+		require.NoError(serr, "SignEntityMetadata")
+		require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	}
+
+	dir := t.TempDir()
+	index, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	require.NoError(index.Verify(), "Verify")
+	require.Equal(fp.Revision(), index.Revision)
+	require.Len(index.Statements[EntityStatementKind.Name], 2)
+	require.Empty(index.Statements[NodeStatementKind.Name])
+
+	// Exporting again results in the same index.
+	again, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	require.Equal(index.Hash, again.Hash)
+
+	// The mirror can be used as a registry.
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	hp, err := NewHTTPProvider(srv.URL)
+	require.NoError(err, "NewHTTPProvider")
+	require.NoError(hp.VerifyUpdate(fp), "VerifyUpdate")
+	require.Equal(index.Revision, hp.Revision())
+	entities, err := hp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+	require.Equal("other world", entities[other.Public()].Name)
+	_, err = hp.GetEntity(ctx, signer.Public())
+	require.True(errors.Is(err, ErrEntityRevoked), "GetEntity should fail for revoked entities")
+
+	// Truncated indexes are detected.
+	index.Statements[EntityStatementKind.Name] = index.Statements[EntityStatementKind.Name][:1]
+	require.Error(index.Verify(), "Verify should fail for modified indexes")
+	data, err := json.Marshal(index)
+	require.NoError(err, "Marshal")
+	require.NoError(os.WriteFile(filepath.Join(dir, IndexFilename), data, 0o600), "WriteFile")
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntities should fail for truncated indexes")
+	require.Empty(hp.Revision())
+}
+
+func TestExportStaticPrune(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	newProvider := func(n int) (MutableStatementProvider, []signature.Signer) {
+		fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+		require.NoError(err, "NewFilesystemProviderWithConfig")
+		require.NoError(fp.Init(), "Init")
+		return fp, newTestEntities(require, fp, n)
+	}
+	fp, signers := newProvider(2)
+	dir := t.TempDir()
+	previous, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	removedPath := filepath.Join(dir, filepath.FromSlash(EntityStatementKind.Path(signers[1].Public())))
+	require.FileExists(removedPath)
+	otherPath := filepath.Join(dir, registryDir, registryEntityDir, "README.md")
+	require.NoError(os.WriteFile(otherPath, []byte("hello world"), 0o600), "WriteFile")
+
+	// Exporting a registry without some statements removes them from the mirror.
+	var removedAddressedPath string
+	for _, entry := range previous.Statements[EntityStatementKind.Name] {
+		if entry.ID == EntityStatementKind.Filename(signers[1].Public()) {
+			removedAddressedPath = filepath.Join(dir, filepath.FromSlash(entry.Path))
+		}
+	}
+	require.FileExists(removedAddressedPath)
+
+	fp, _ = newProvider(1)
+	index, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	require.Len(index.Statements[EntityStatementKind.Name], 1)
+	require.NoFileExists(removedPath)
+	require.FileExists(filepath.Join(dir, filepath.FromSlash(EntityStatementKind.Path(signers[0].Public()))))
+	require.FileExists(otherPath, "files other than statements should be kept")
+
+	// Statements referenced by the previous index are only removed by the next export.
+	require.FileExists(removedAddressedPath)
+	_, err = ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	require.NoFileExists(removedAddressedPath)
+	require.NoDirExists(filepath.Dir(removedAddressedPath))
+}
+
+func TestExportStaticUpdate(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	signers := newTestEntities(require, fp, 2)
+	dir := t.TempDir()
+	previous, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+
+	signed, err := SignEntityMetadata(signers[0], &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    2,
+		Name:      "updated entity 0",
+	})
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	index, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	require.NotEqual(previous.Hash, index.Hash)
+
+	// Statements referenced by both indexes still match them, so clients which fetched the
+	// previous index are not affected by the update.
+	for _, idx := range []*Index{previous, index} {
+		for _, entry := range idx.Statements[EntityStatementKind.Name] {
+			id, perr := EntityStatementKind.ParseFilename(entry.ID)
+			require.NoError(perr, "ParseFilename")
+			stmtPath, perr := entry.StatementPath(EntityStatementKind, id)
+			require.NoError(perr, "StatementPath")
+			data, rerr := os.ReadFile(filepath.Join(dir, filepath.FromSlash(stmtPath)))
+			require.NoError(rerr, "ReadFile")
+			require.NoError(entry.Verify(EntityStatementKind, data), "Verify")
+		}
+	}
+
+	// Statements are also available at the same paths as in the registry repository.
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	hp, err := NewHTTPProvider(srv.URL)
+	require.NoError(err, "NewHTTPProvider")
+	entity, err := hp.GetEntity(ctx, signers[0].Public())
+	require.NoError(err, "GetEntity")
+	require.Equal("updated entity 0", entity.Name)
+
+	// Indexes referencing statements outside of the statement directory are rejected.
+	index.Statements[EntityStatementKind.Name][0].Path = "index.json"
+	index.Hash = index.ComputeHash()
+	data, err := json.Marshal(index)
+	require.NoError(err, "Marshal")
+	require.NoError(os.WriteFile(filepath.Join(dir, IndexFilename), data, 0o600), "WriteFile")
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntities should fail for bad statement paths")
+}
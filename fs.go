@@ -2,26 +2,35 @@ package registry
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 )
 
 const (
-	registryDir       = "registry"
-	registryEntityDir = "entity"
+	registryDir        = "registry"
+	registryEntityDir  = "entity"
+	registryNodeDir    = "node"
+	registryRuntimeDir = "runtime"
 
 	placeholderFilename = ".placeholder"
 	statementExt        = ".json"
 )
 
 // MutableProvider is a mutable registry provider interface.
+//
+// All mutable providers created by this package additionally implement MutableStatementProvider.
 type MutableProvider interface {
 	Provider
 
@@ -35,111 +44,408 @@ type MutableProvider interface {
 	UpdateEntity(entity *SignedEntityMetadata) error
 }
 
+// MutableStatementProvider is a mutable registry provider supporting statements of all kinds. It
+// is implemented by all mutable providers created by this package, but is kept separate from
+// MutableProvider so that existing MutableProvider implementations remain valid.
+type MutableStatementProvider interface {
+	MutableProvider
+	StatementProvider
+
+	// UpdateNode updates node metadata in the registry.
+	UpdateNode(node *SignedNodeMetadata) error
+
+	// UpdateRuntime updates runtime metadata in the registry.
+	UpdateRuntime(runtime *SignedRuntimeMetadata) error
+
+	// UpdateStatement updates a statement of the given kind in the registry.
+	UpdateStatement(ctx context.Context, kind *StatementKind, signed *signature.Signed) error
+
+	// VerifyWithReport verifies the integrity of the whole registry and, when src is not nil, of
+	// a registry update from src. Instead of stopping at the first failure, it returns a report
+	// of all checked statements.
+	VerifyWithReport(ctx context.Context, src Provider) (*VerifyReport, error)
+}
+
+// FilesystemConfig contains the configuration of the filesystem provider.
+type FilesystemConfig struct {
+	// Workers is the maximum number of statements loaded and verified in parallel. If zero, the
+	// number of CPUs usable by the process (GOMAXPROCS) is used.
+	Workers int
+
+	// RuntimeOwners is the lookup used to verify that new runtime statements are signed by the
+	// entity owning the runtime (see VerifyOptions).
+	RuntimeOwners RuntimeOwnerLookup
+}
+
 type fsProvider struct {
 	baseDir string
 	fs      billy.Filesystem
+	workers int
+
+	// verifyOpts are the options of verifying new and updated statements.
+	verifyOpts VerifyOptions
+
+	// revision is the revision of an immutable registry (e.g. the hash of a Git commit). When
+	// empty, the revision is derived from the registry contents.
+	revision string
 }
 
 // Implements Provider.
 func (p *fsProvider) Verify() error {
-	_, err := p.GetEntities(context.Background())
-	return err
+	return p.VerifyContext(context.Background())
 }
 
 // Implements Provider.
 func (p *fsProvider) VerifyUpdate(src Provider) error {
-	ctx := context.Background()
-	dstEnts, err := p.GetEntities(ctx)
+	return p.VerifyUpdateContext(context.Background(), src)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) VerifyContext(ctx context.Context) error {
+	return verifyStatements(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) VerifyUpdateContext(ctx context.Context, src Provider) error {
+	return verifyStatementsUpdates(ctx, p, src, &p.verifyOpts)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetStatements(ctx context.Context, kind *StatementKind) (map[StatementID]Statement, error) {
+	signed, err := p.GetSignedStatements(ctx, kind)
 	if err != nil {
-		return fmt.Errorf("destination registry is corrupted: %w", err)
+		return nil, err
 	}
+	return unsignedStatements(signed), nil
+}
 
-	srcEnts, err := src.GetEntities(ctx)
+// Implements StatementProvider.
+func (p *fsProvider) GetSignedStatements(
+	ctx context.Context,
+	kind *StatementKind,
+) (map[StatementID]*SignedStatement, error) {
+	files, err := p.readStatementDir(kind)
 	if err != nil {
-		return fmt.Errorf("source registry is corrupted: %w", err)
+		return nil, err
 	}
 
-	// No entites can be removed by an update.
-	for id := range srcEnts {
-		if dstEnts[id] == nil {
-			return fmt.Errorf("entity statement has been removed: %s", id)
+	var stmtFiles []os.FileInfo
+	for _, fi := range files {
+		if filepath.Ext(fi.Name()) == statementExt {
+			stmtFiles = append(stmtFiles, fi)
 		}
 	}
 
-	// Updated entities must use a higher serial number.
-	for id, dst := range dstEnts {
-		var src *EntityMetadata
-		if src = srcEnts[id]; src == nil {
-			// New entity.
-			continue
+	// Statements are loaded and verified in parallel.
+	ids := make([]StatementID, len(stmtFiles))
+	stmts := make([]*SignedStatement, len(stmtFiles))
+	err = forEachParallel(ctx, p.workers, len(stmtFiles), func(i int) error {
+		fi := stmtFiles[i]
+		id, result, err := p.loadStatementFile(ctx, kind, fi)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrBadFilename), errors.Is(err, ErrStatementTooBig):
+			return err
+		default:
+			return fmt.Errorf("%w: %s: bad statement '%s': %w", ErrCorruptedRegistry, kind.Name, fi.Name(), err)
 		}
 
-		if !src.Equal(dst) && dst.Serial <= src.Serial {
-			return fmt.Errorf("updated entity '%s' metadata must increase serial number (existing: %d provided: %d)",
-				id,
-				src.Serial,
-				dst.Serial,
-			)
-		}
+		ids[i], stmts[i] = id, result
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	results := make(map[StatementID]*SignedStatement, len(stmtFiles))
+	for i, id := range ids {
+		results[id] = stmts[i]
+	}
+	return results, nil
 }
 
-// Implements Provider.
-func (p *fsProvider) GetEntities(ctx context.Context) (map[signature.PublicKey]*EntityMetadata, error) {
-	entities, err := p.fs.ReadDir(p.fs.Join(registryDir, registryEntityDir))
+// readStatementDir reads the directory containing statements of the given kind. Optional
+// directories which do not exist are treated as empty.
+func (p *fsProvider) readStatementDir(kind *StatementKind) ([]os.FileInfo, error) {
+	files, err := p.fs.ReadDir(p.fs.Join(registryDir, kind.Dir))
+	switch {
+	case err == nil:
+		return files, nil
+	case os.IsNotExist(err) && kind.Optional:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: failed to read %s directory: %s", ErrCorruptedRegistry, kind.Name, err)
+	}
+}
+
+// loadStatementFile loads and verifies the statement of the given kind described by the given
+// directory entry.
+func (p *fsProvider) loadStatementFile(
+	ctx context.Context,
+	kind *StatementKind,
+	fi os.FileInfo,
+) (StatementID, *SignedStatement, error) {
+	id, err := checkStatementFile(kind, fi)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to read entity directory: %s", ErrCorruptedRegistry, err)
+		return id, nil, err
 	}
 
-	results := make(map[signature.PublicKey]*EntityMetadata)
-	for _, fi := range entities {
+	result, err := p.GetSignedStatement(ctx, kind, id)
+	if err != nil {
+		return id, nil, err
+	}
+	return id, result, nil
+}
+
+// checkStatementFile checks the filename and the size of the statement of the given kind
+// described by the given directory entry and returns the identifier encoded in the filename.
+func checkStatementFile(kind *StatementKind, fi os.FileInfo) (StatementID, error) {
+	id, err := kind.ParseFilename(strings.TrimSuffix(fi.Name(), statementExt))
+	if err != nil {
+		return nil, newStatementError(ErrBadFilename,
+			fmt.Errorf("%w: %s: bad statement filename '%s': %s", ErrCorruptedRegistry, kind.Name, fi.Name(), err),
+		)
+	}
+
+	if maxSize := kind.maxSize(); fi.Size() > maxSize {
+		return id, newStatementError(ErrStatementTooBig,
+			fmt.Errorf(
+				"%w: %s: statement too big (size: %d max: %d): %s",
+				ErrCorruptedRegistry, kind.Name, fi.Size(), maxSize, fi.Name(),
+			),
+		)
+	}
+	return id, nil
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetStatement(ctx context.Context, kind *StatementKind, id StatementID) (Statement, error) {
+	f, err := p.openStatement(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return kind.Load(id, f)
+}
+
+// openStatement opens the file containing the statement of the given kind.
+func (p *fsProvider) openStatement(kind *StatementKind, id StatementID) (billy.File, error) {
+	f, err := p.fs.Open(kind.Path(id))
+	switch {
+	case err == nil:
+		return f, nil
+	case os.IsNotExist(err):
+		return nil, kind.ErrNoSuchStatement
+	default:
+		return nil, fmt.Errorf("%w: failed to open %s metadata: %s", ErrCorruptedRegistry, kind.Name, err)
+	}
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetSignedStatement(
+	ctx context.Context,
+	kind *StatementKind,
+	id StatementID,
+) (*SignedStatement, error) {
+	f, err := p.openStatement(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stmt := kind.New()
+	signed, err := kind.loadSigned(id, f, stmt)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedStatement{
+		Statement: stmt,
+		Signed:    signed,
+	}, nil
+}
+
+// Implements StatementSource.
+func (p *fsProvider) GetRawStatements(ctx context.Context, kind *StatementKind) (map[string][]byte, error) {
+	files, err := p.readStatementDir(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]byte)
+	for _, fi := range files {
 		if filepath.Ext(fi.Name()) != statementExt {
 			continue
 		}
 
-		var id signature.PublicKey
-		if err = id.UnmarshalHex(strings.TrimSuffix(fi.Name(), statementExt)); err != nil {
-			return nil, fmt.Errorf("%w: entity: bad statement filename '%s': %s", ErrCorruptedRegistry, fi.Name(), err)
+		id, err := checkStatementFile(kind, fi)
+		if err != nil {
+			return nil, err
 		}
-
-		if fi.Size() > MaxStatementSize {
-			return nil, fmt.Errorf(
-				"%w: entity: statement too big (size: %d max: %d): %s",
-				ErrCorruptedRegistry, fi.Size(), MaxStatementSize, fi.Name(),
-			)
+		data, err := p.GetRawStatement(ctx, kind, id)
+		if err != nil {
+			return nil, err
 		}
+		results[strings.TrimSuffix(fi.Name(), statementExt)] = data
+	}
+	return results, nil
+}
 
-		var result *EntityMetadata
-		if result, err = p.GetEntity(context.Background(), id); err != nil {
-			return nil, fmt.Errorf("%w: entity: bad statement '%s': %s", ErrCorruptedRegistry, fi.Name(), err)
-		}
+// Implements StatementSource.
+func (p *fsProvider) GetRawStatement(ctx context.Context, kind *StatementKind, id StatementID) ([]byte, error) {
+	f, err := p.openStatement(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-		results[id] = result
+	// Read one more byte than allowed so that oversized statements can be detected.
+	data, err := io.ReadAll(io.LimitReader(f, kind.maxSize()+1))
+	if err != nil {
+		return nil, newStatementError(ErrMalformedStatement,
+			fmt.Errorf("%w: failed to read %s metadata: %s", ErrCorruptedRegistry, kind.Name, err),
+		)
 	}
-	return results, nil
+	return data, nil
+}
+
+// Implements StatementProvider.
+//
+// The revision of a registry in a local directory is derived from the names, sizes and
+// modification times of its statement files, so it is cheap to compute but a statement replaced
+// by one of the same size within the timestamp resolution of the filesystem is not detected.
+// The revision of any other filesystem (e.g. an in-memory one) is the hash of all statements.
+func (p *fsProvider) Revision() string {
+	if p.revision != "" {
+		return p.revision
+	}
+
+	h := sha256.New()
+	for _, kind := range StatementKinds() {
+		files, err := p.readStatementDir(kind)
+		if err != nil {
+			return ""
+		}
+		for _, fi := range files {
+			if filepath.Ext(fi.Name()) != statementExt {
+				continue
+			}
+
+			if p.baseDir != "" {
+				fmt.Fprintf(h, "%s/%s %d %d\n", kind.Dir, fi.Name(), fi.Size(), fi.ModTime().UnixNano())
+				continue
+			}
+
+			data, err := util.ReadFile(p.fs, p.fs.Join(registryDir, kind.Dir, fi.Name()))
+			if err != nil {
+				return ""
+			}
+			fmt.Fprintf(h, "%s/%s %x\n", kind.Dir, fi.Name(), sha256.Sum256(data))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-func (p *fsProvider) getEntityPath(id signature.PublicKey) string {
-	entityID := publicKeyToFilename(id)
-	return p.fs.Join(registryDir, registryEntityDir, entityID+statementExt)
+// Implements Provider.
+func (p *fsProvider) GetEntities(ctx context.Context) (map[signature.PublicKey]*EntityMetadata, error) {
+	return getEntities(ctx, p)
 }
 
 // Implements Provider.
 func (p *fsProvider) GetEntity(ctx context.Context, id signature.PublicKey) (*EntityMetadata, error) {
-	f, err := p.fs.Open(p.getEntityPath(id))
-	switch {
-	case err == nil:
-	case os.IsNotExist(err):
-		return nil, ErrNoSuchEntity
-	default:
-		return nil, fmt.Errorf("%w: failed to open entity metadata: %s", ErrCorruptedRegistry, err)
+	return getEntity(ctx, p, id)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetNodes(ctx context.Context) (map[signature.PublicKey]*NodeMetadata, error) {
+	return getNodes(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetNode(ctx context.Context, id signature.PublicKey) (*NodeMetadata, error) {
+	return getNode(ctx, p, id)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetRuntimes(ctx context.Context) (map[common.Namespace]*RuntimeMetadata, error) {
+	return getRuntimes(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *fsProvider) GetRuntime(ctx context.Context, id common.Namespace) (*RuntimeMetadata, error) {
+	return getRuntime(ctx, p, id)
+}
+
+// Implements MutableStatementProvider.
+func (p *fsProvider) VerifyWithReport(ctx context.Context, src Provider) (*VerifyReport, error) {
+	report := new(VerifyReport)
+	for _, kind := range StatementKinds() {
+		if err := p.reportStatements(ctx, report, kind, src); err != nil {
+			return nil, err
+		}
 	}
-	defer f.Close()
 
-	entity := new(EntityMetadata)
-	return entity, entity.Load(id, f)
+	report.sort()
+	return report, nil
+}
+
+// reportStatements adds the verification results of all statements of the given kind to the
+// report.
+func (p *fsProvider) reportStatements(
+	ctx context.Context,
+	report *VerifyReport,
+	kind *StatementKind,
+	src Provider,
+) error {
+	files, err := p.readStatementDir(kind)
+	if err != nil {
+		return err
+	}
+
+	var srcStmts map[StatementID]Statement
+	if src != nil {
+		if srcStmts, err = getSourceStatements(ctx, src, kind); err != nil {
+			return fmt.Errorf("source registry is corrupted: %w", err)
+		}
+	}
+
+	seen := make(map[StatementID]bool)
+	for _, fi := range files {
+		if filepath.Ext(fi.Name()) != statementExt {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		path := p.fs.Join(registryDir, kind.Dir, fi.Name())
+		id, dst, err := p.loadStatementFile(ctx, kind, fi)
+		if errors.Is(err, ErrBadFilename) {
+			report.add(kind, path, "", err)
+			continue
+		}
+		seen[id] = true
+
+		switch srcStmt := srcStmts[id]; {
+		case err != nil || src == nil:
+			// Statement failed to load or no update is being verified.
+		case srcStmt == nil:
+			err = verifyStatementCreate(ctx, p, kind, id, dst.Statement, &p.verifyOpts)
+		case !statementsEqual(srcStmt, dst.Statement):
+			err = verifyStatementUpdate(ctx, p, kind, id, srcStmt, dst.Statement)
+		}
+		report.add(kind, path, id.String(), err)
+	}
+
+	// No statements can be removed by an update.
+	for id := range srcStmts {
+		if !seen[id] {
+			report.add(kind, kind.Path(id), id.String(),
+				newStatementError(kind.ErrRemoved, fmt.Errorf("%s statement has been removed", kind.Name)),
+			)
+		}
+	}
+	return nil
 }
 
 // Implements MutableProvider.
@@ -149,9 +455,9 @@ func (p *fsProvider) BaseDir() string {
 
 // Implements MutableProvider.
 func (p *fsProvider) Init() error {
-	paths := []string{
-		registryDir,
-		p.fs.Join(registryDir, registryEntityDir),
+	paths := []string{registryDir}
+	for _, kind := range StatementKinds() {
+		paths = append(paths, p.fs.Join(registryDir, kind.Dir))
 	}
 
 	for _, path := range paths {
@@ -174,52 +480,84 @@ func (p *fsProvider) Init() error {
 	return nil
 }
 
-// Implements MutableProvider.
-func (p *fsProvider) UpdateEntity(entity *SignedEntityMetadata) error {
-	// Make sure the signed entity is valid before processing it.
-	var inner EntityMetadata
-	if err := entity.Open(&inner); err != nil {
-		return fmt.Errorf("bad signed entity metadata: %w", err)
-	}
-	if err := inner.ValidateBasic(); err != nil {
-		return fmt.Errorf("bad signed entity metadata: %w", err)
-	}
-
-	// Check if the entity already exists. In this case, require that the serial number is bumped.
-	existing, err := p.GetEntity(context.Background(), entity.Signature.PublicKey)
-	switch err {
-	case nil:
-		if inner.Serial <= existing.Serial {
-			return fmt.Errorf("updated entity metadata must increase serial number (existing: %d provided: %d)",
-				existing.Serial,
-				inner.Serial,
-			)
+// Implements MutableStatementProvider.
+func (p *fsProvider) UpdateStatement(ctx context.Context, kind *StatementKind, signed *signature.Signed) error {
+	// Make sure the signed statement is valid before processing it.
+	id, stmt, err := kind.Open(signed)
+	if err != nil {
+		return err
+	}
+
+	// Check if the statement already exists. In this case, require that it is a valid update.
+	existing, err := p.GetStatement(ctx, kind, id)
+	switch {
+	case err == nil:
+		if err = kind.verifyUpdate(existing, stmt, signed.Signature.PublicKey); err != nil {
+			return err
+		}
+	case errors.Is(err, kind.ErrNoSuchStatement):
+		if err = kind.verifyCreate(ctx, &p.verifyOpts, stmt, signed.Signature.PublicKey); err != nil {
+			return err
 		}
-	case ErrNoSuchEntity:
 	default:
-		return fmt.Errorf("failed to query for existing entity: %w", err)
+		return fmt.Errorf("failed to query for existing %s: %w", kind.Name, err)
 	}
 
-	f, err := p.fs.Create(p.getEntityPath(entity.Signature.PublicKey))
+	f, err := p.fs.Create(kind.Path(id))
 	if err != nil {
-		return fmt.Errorf("failed to create entity metadata file: %w", err)
+		return fmt.Errorf("failed to create %s metadata file: %w", kind.Name, err)
 	}
 	defer f.Close()
 
-	return entity.Save(f)
+	return saveSigned(f, signed)
+}
+
+// Implements MutableProvider.
+func (p *fsProvider) UpdateEntity(entity *SignedEntityMetadata) error {
+	return p.UpdateStatement(context.Background(), EntityStatementKind, &entity.Signed)
+}
+
+// Implements MutableStatementProvider.
+func (p *fsProvider) UpdateNode(node *SignedNodeMetadata) error {
+	return p.UpdateStatement(context.Background(), NodeStatementKind, &node.Signed)
+}
+
+// Implements MutableStatementProvider.
+func (p *fsProvider) UpdateRuntime(runtime *SignedRuntimeMetadata) error {
+	return p.UpdateStatement(context.Background(), RuntimeStatementKind, &runtime.Signed)
 }
 
 // NewFilesystemProvider creates a new filesystem-based registry interface.
 func NewFilesystemProvider(fs billy.Filesystem) (MutableProvider, error) {
-	return &fsProvider{fs: fs}, nil
+	return NewFilesystemProviderWithConfig(fs, FilesystemConfig{})
+}
+
+// NewFilesystemProviderWithConfig creates a new filesystem-based registry interface with the given
+// configuration.
+func NewFilesystemProviderWithConfig(fs billy.Filesystem, cfg FilesystemConfig) (MutableStatementProvider, error) {
+	return newFilesystemProvider("", fs, cfg), nil
 }
 
 // NewFilesystemPathProvider creates a new filesystem-based registry interface for the given path.
 func NewFilesystemPathProvider(path string) (MutableProvider, error) {
+	return NewFilesystemPathProviderWithConfig(path, FilesystemConfig{})
+}
+
+// NewFilesystemPathProviderWithConfig creates a new filesystem-based registry interface for the
+// given path with the given configuration.
+func NewFilesystemPathProviderWithConfig(path string, cfg FilesystemConfig) (MutableStatementProvider, error) {
+	return newFilesystemProvider(path, osfs.New(path), cfg), nil
+}
+
+func newFilesystemProvider(baseDir string, fs billy.Filesystem, cfg FilesystemConfig) *fsProvider {
 	return &fsProvider{
-		baseDir: path,
-		fs:      osfs.New(path),
-	}, nil
+		baseDir: baseDir,
+		fs:      fs,
+		workers: cfg.Workers,
+		verifyOpts: VerifyOptions{
+			RuntimeOwners: cfg.RuntimeOwners,
+		},
+	}
 }
 
 func publicKeyToFilename(pk signature.PublicKey) string {
@@ -1,15 +1,37 @@
 package registry
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 	"github.com/stretchr/testify/require"
 )
 
+func newTestEntities(require *require.Assertions, fp MutableProvider, n int) []signature.Signer {
+	signers := make([]signature.Signer, 0, n)
+	for i := 0; i < n; i++ {
+		signer := memorySigner.NewTestSigner(fmt.Sprintf("metadata-registry-tools test entity signer %d", i))
+		signed, err := SignEntityMetadata(signer, &EntityMetadata{
+			Versioned: cbor.NewVersioned(1),
+			Serial:    1,
+			Name:      fmt.Sprintf("entity %d", i),
+		})
+		require.NoError(err, "SignEntityMetadata")
+		require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+		signers = append(signers, signer)
+	}
+	return signers
+}
+
 func TestFilesystemProvider(t *testing.T) {
 	require := require.New(t)
 
@@ -41,6 +63,7 @@ func TestFilesystemProvider(t *testing.T) {
 	require.NoError(err, "UpdateEntity")
 	err = fp.UpdateEntity(signed)
 	require.Error(err, "UpdateEntity should fail if serial number is not bumped")
+	require.True(errors.Is(err, ErrSerialNotIncreased))
 
 	fetchedEntity, err := fp.GetEntity(context.Background(), signer.Public())
 	require.NoError(err, "GetEntity")
@@ -62,3 +85,399 @@ func TestFilesystemProvider(t *testing.T) {
 	require.Len(entities, 1)
 	require.EqualValues(entity, entities[signer.Public()])
 }
+
+func TestFilesystemProviderVerifyWithReport(t *testing.T) {
+	require := require.New(t)
+
+	writeFile := func(fs billy.Filesystem, name string, data []byte) {
+		f, err := fs.Create(fs.Join(registryDir, registryEntityDir, name))
+		require.NoError(err, "Create")
+		defer f.Close()
+		_, err = f.Write(data)
+		require.NoError(err, "Write")
+	}
+	sign := func(signer signature.Signer, entity *EntityMetadata) []byte {
+		signed, err := SignEntityMetadata(signer, entity)
+		require.NoError(err, "SignEntityMetadata")
+		var buf bytes.Buffer
+		err = signed.Save(&buf)
+		require.NoError(err, "Save")
+		return buf.Bytes()
+	}
+
+	var signers []signature.Signer
+	for i := 0; i < 6; i++ {
+		signers = append(signers, memorySigner.NewTestSigner(fmt.Sprintf("metadata-registry-tools test entity signer %d", i)))
+	}
+
+	// Previous registry snapshot.
+	srcFs := memfs.New()
+	src, err := NewFilesystemProviderWithConfig(srcFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	err = src.Init()
+	require.NoError(err, "Init")
+	for _, signer := range signers[:2] {
+		signed, err := SignEntityMetadata(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2})
+		require.NoError(err, "SignEntityMetadata")
+		err = src.UpdateEntity(signed)
+		require.NoError(err, "UpdateEntity")
+	}
+
+	// Updated registry snapshot.
+	dstFs := memfs.New()
+	dst, err := NewFilesystemProviderWithConfig(dstFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	err = dst.Init()
+	require.NoError(err, "Init")
+
+	report, err := dst.VerifyWithReport(context.Background(), nil)
+	require.NoError(err, "VerifyWithReport")
+	require.Empty(report.Entries, "VerifyWithReport should work on an empty registry")
+	require.False(report.Failed())
+
+	// Entity 0 has been removed, entity 1 regresses the serial number.
+	writeFile(dstFs, publicKeyToFilename(signers[1].Public())+statementExt,
+		sign(signers[1], &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "regression"}),
+	)
+	// Entity 2 is valid.
+	writeFile(dstFs, publicKeyToFilename(signers[2].Public())+statementExt,
+		sign(signers[2], &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1}),
+	)
+	// Entity 3 is signed by a different signer.
+	writeFile(dstFs, publicKeyToFilename(signers[3].Public())+statementExt,
+		sign(signers[2], &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1}),
+	)
+	// Entity 4 has a bad signature.
+	badSig, err := SignEntityMetadata(signers[4], &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1})
+	require.NoError(err, "SignEntityMetadata")
+	badSig.Signature.Signature[0] ^= 0xff
+	var buf bytes.Buffer
+	err = badSig.Save(&buf)
+	require.NoError(err, "Save")
+	writeFile(dstFs, publicKeyToFilename(signers[4].Public())+statementExt, buf.Bytes())
+	// Entity 5 fails validation.
+	writeFile(dstFs, publicKeyToFilename(signers[5].Public())+statementExt,
+		sign(signers[5], &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, URL: "http://insecure"}),
+	)
+	// Bad filename and too big statements.
+	writeFile(dstFs, "bad"+statementExt, []byte("{}"))
+	writeFile(dstFs, publicKeyToFilename(signature.PublicKey{})+statementExt, make([]byte, MaxStatementSize+1))
+
+	report, err = dst.VerifyWithReport(context.Background(), src)
+	require.NoError(err, "VerifyWithReport")
+	require.True(report.Failed())
+
+	statuses := make(map[string]VerifyStatus)
+	for _, entry := range report.Entries {
+		statuses[entry.ID] = entry.Status
+		if entry.Status == VerifyStatusOK {
+			require.Empty(entry.Error)
+		} else {
+			require.NotEmpty(entry.Error)
+		}
+	}
+	require.Len(report.Entries, 8)
+	require.Equal(VerifyStatusBadFilename, statuses[""])
+	require.Equal(VerifyStatusTooBig, statuses[signature.PublicKey{}.String()])
+	require.Equal(VerifyStatusRemoved, statuses[signers[0].Public().String()])
+	require.Equal(VerifyStatusSerialRegression, statuses[signers[1].Public().String()])
+	require.Equal(VerifyStatusOK, statuses[signers[2].Public().String()])
+	require.Equal(VerifyStatusSignerMismatch, statuses[signers[3].Public().String()])
+	require.Equal(VerifyStatusBadSignature, statuses[signers[4].Public().String()])
+	require.Equal(VerifyStatusValidationFailure, statuses[signers[5].Public().String()])
+
+	// Verify typed errors returned by VerifyUpdate.
+	dst, err = NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	err = dst.Init()
+	require.NoError(err, "Init")
+	err = dst.VerifyUpdate(src)
+	require.True(errors.Is(err, ErrEntityRemoved), "VerifyUpdate should fail with a removed entity")
+
+	for i, signer := range signers[:2] {
+		signed, err := SignEntityMetadata(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: uint64(i + 1)})
+		require.NoError(err, "SignEntityMetadata")
+		err = dst.UpdateEntity(signed)
+		require.NoError(err, "UpdateEntity")
+	}
+	err = dst.VerifyUpdate(src)
+	require.True(errors.Is(err, ErrSerialNotIncreased), "VerifyUpdate should fail with a serial regression")
+}
+
+func TestFilesystemProviderRevocation(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	update := func(fp MutableProvider, signer signature.Signer, entity *EntityMetadata) error {
+		signed, err := SignEntityMetadata(signer, entity)
+		require.NoError(err, "SignEntityMetadata")
+		return fp.UpdateEntity(signed)
+	}
+
+	srcFs := memfs.New()
+	src, err := NewFilesystemProviderWithConfig(srcFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(src.Init(), "Init")
+	entity := &EntityMetadata{Versioned: cbor.NewVersioned(2), Serial: 1, Name: "hello world"}
+	require.NoError(update(src, signer, entity), "UpdateEntity")
+	require.NoError(update(src, other, entity), "UpdateEntity")
+
+	// Revoke the entity's metadata in an updated registry.
+	dstFs := memfs.New()
+	dst, err := NewFilesystemProviderWithConfig(dstFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(dst.Init(), "Init")
+	require.NoError(update(dst, signer, entity), "UpdateEntity")
+	require.NoError(update(dst, other, entity), "UpdateEntity")
+	revocation := &EntityMetadata{Versioned: cbor.NewVersioned(2), Serial: 2, Revoked: true}
+	require.NoError(update(dst, signer, revocation), "UpdateEntity should accept a revocation tombstone")
+	require.NoError(dst.Verify(), "Verify")
+	require.NoError(dst.VerifyUpdate(src), "VerifyUpdate should accept a revocation tombstone")
+
+	fetched, err := dst.GetEntity(ctx, signer.Public())
+	require.True(errors.Is(err, ErrEntityRevoked), "GetEntity should fail for revoked entities")
+	require.EqualValues(revocation, fetched, "GetEntity should return the revocation tombstone")
+	_, err = dst.GetEntity(ctx, other.Public())
+	require.NoError(err, "GetEntity")
+
+	entities, err := dst.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1, "GetEntities should skip revoked entities")
+	require.NotNil(entities[other.Public()])
+
+	stmts, err := dst.GetStatements(ctx, EntityStatementKind)
+	require.NoError(err, "GetStatements")
+	require.Len(stmts, 2, "GetStatements should include revocation tombstones")
+
+	// Revoked metadata cannot be updated anymore.
+	entity.Serial = 3
+	err = update(dst, signer, entity)
+	require.True(errors.Is(err, ErrEntityRevoked), "UpdateEntity should fail for revoked entities")
+	revocation.Serial = 3
+	err = update(dst, signer, revocation)
+	require.True(errors.Is(err, ErrEntityRevoked), "UpdateEntity should fail for revoked entities")
+
+	// Neither can it be restored by overwriting the tombstone.
+	restoredFs := memfs.New()
+	restored, err := NewFilesystemProviderWithConfig(restoredFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(restored.Init(), "Init")
+	require.NoError(update(restored, signer, entity), "UpdateEntity")
+	require.NoError(update(restored, other, &EntityMetadata{
+		Versioned: cbor.NewVersioned(2),
+		Serial:    1,
+		Name:      "hello world",
+	}))
+	err = restored.VerifyUpdate(dst)
+	require.True(errors.Is(err, ErrEntityRevoked), "VerifyUpdate should fail for updated revoked entities")
+
+	report, err := restored.VerifyWithReport(context.Background(), dst)
+	require.NoError(err, "VerifyWithReport")
+	require.True(report.Failed())
+	for _, entry := range report.Entries {
+		switch entry.ID {
+		case signer.Public().String():
+			require.Equal(VerifyStatusRevokedUpdate, entry.Status)
+		default:
+			require.Equal(VerifyStatusOK, entry.Status)
+		}
+	}
+}
+
+// entityProvider hides all but the Provider methods of the wrapped provider, like providers
+// implemented outside of this package.
+type entityProvider struct {
+	Provider
+}
+
+func TestFilesystemProviderVerifyUpdateEntityProvider(t *testing.T) {
+	require := require.New(t)
+
+	src, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(src.Init(), "Init")
+	signers := newTestEntities(require, src, 2)
+
+	dstFs := memfs.New()
+	dst, err := NewFilesystemProviderWithConfig(dstFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(dst.Init(), "Init")
+	newTestEntities(require, dst, 2)
+
+	// Entities are verified against providers only implementing the Provider interface.
+	require.NoError(dst.VerifyUpdate(&entityProvider{src}), "VerifyUpdate")
+	report, err := dst.VerifyWithReport(context.Background(), &entityProvider{src})
+	require.NoError(err, "VerifyWithReport")
+	require.False(report.Failed(), "VerifyWithReport")
+
+	require.NoError(dstFs.Remove(EntityStatementKind.Path(signers[1].Public())), "Remove")
+	err = dst.VerifyUpdate(&entityProvider{src})
+	require.True(errors.Is(err, ErrEntityRemoved), "VerifyUpdate should fail for removed entities")
+}
+
+func TestFilesystemProviderSignedStatements(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	emptyRevision := fp.Revision()
+	require.NotEmpty(emptyRevision, "Revision")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	_, err = fp.GetSignedStatement(ctx, EntityStatementKind, signer.Public())
+	require.Equal(ErrNoSuchEntity, err)
+
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "hello world",
+	}
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+
+	fetched, err := fp.GetSignedStatement(ctx, EntityStatementKind, signer.Public())
+	require.NoError(err, "GetSignedStatement")
+	require.EqualValues(&signed.Signed, fetched.Signed)
+	require.EqualValues(entity, fetched.Statement)
+
+	fetchedAll, err := fp.GetSignedStatements(ctx, EntityStatementKind)
+	require.NoError(err, "GetSignedStatements")
+	require.Len(fetchedAll, 1)
+	require.EqualValues(fetched, fetchedAll[signer.Public()])
+
+	// The revision only changes when the registry is updated.
+	revision := fp.Revision()
+	require.NotEqual(emptyRevision, revision)
+	require.Equal(revision, fp.Revision())
+
+	entity.Serial++
+	signed, err = SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	require.NotEqual(revision, fp.Revision())
+}
+
+func TestFilesystemPathProviderRevision(t *testing.T) {
+	require := require.New(t)
+
+	p, err := NewFilesystemPathProvider(t.TempDir())
+	require.NoError(err, "NewFilesystemPathProvider")
+	fp := p.(MutableStatementProvider)
+	require.NoError(fp.Init(), "Init")
+	emptyRevision := fp.Revision()
+	require.NotEmpty(emptyRevision, "Revision")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "hello world",
+	}
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+
+	// The revision only changes when the registry is updated.
+	revision := fp.Revision()
+	require.NotEqual(emptyRevision, revision)
+	require.Equal(revision, fp.Revision())
+
+	entity.Serial++
+	entity.Name = "hello updated world"
+	signed, err = SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	require.NotEqual(revision, fp.Revision())
+}
+
+func TestFilesystemProviderParallel(t *testing.T) {
+	require := require.New(t)
+
+	fs := memfs.New()
+	fp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{Workers: 1})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	signers := newTestEntities(require, fp, 20)
+
+	serial, err := fp.GetEntities(context.Background())
+	require.NoError(err, "GetEntities")
+	require.Len(serial, 20)
+
+	pp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{Workers: 4})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	parallel, err := pp.GetEntities(context.Background())
+	require.NoError(err, "GetEntities")
+	require.EqualValues(serial, parallel)
+
+	// Cancelled contexts are honored.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pp.GetEntities(ctx)
+	require.True(errors.Is(err, context.Canceled), "GetEntities should fail for cancelled contexts")
+	_, err = NewCachingProvider(pp, CacheOptions{}).GetEntities(ctx)
+	require.True(errors.Is(err, context.Canceled), "GetEntities should fail for cancelled contexts")
+	err = pp.(StatementProvider).VerifyContext(ctx)
+	require.True(errors.Is(err, context.Canceled), "VerifyContext should fail for cancelled contexts")
+	_, err = pp.(MutableStatementProvider).VerifyWithReport(ctx, nil)
+	require.True(errors.Is(err, context.Canceled), "VerifyWithReport should fail for cancelled contexts")
+
+	// Workers stop once the context is done, even for statements which have been dispatched.
+	ctx, cancel = context.WithCancel(context.Background())
+	var calls int
+	err = forEachParallel(ctx, 1, 10, func(int) error {
+		calls++
+		cancel()
+		return nil
+	})
+	require.True(errors.Is(err, context.Canceled), "forEachParallel should fail for cancelled contexts")
+	require.Equal(1, calls, "forEachParallel should not make calls after the context is done")
+
+	// The same error is reported regardless of the number of workers.
+	for _, signer := range []signature.Signer{signers[3], signers[7], signers[11]} {
+		path := EntityStatementKind.Path(signer.Public())
+		require.NoError(util.WriteFile(fs, path, []byte("corrupted"), 0o644), "WriteFile")
+	}
+	_, serialErr := fp.GetEntities(context.Background())
+	require.True(errors.Is(serialErr, ErrCorruptedRegistry), "GetEntities should fail for corrupted statements")
+	for i := 0; i < 10; i++ {
+		_, err = pp.GetEntities(context.Background())
+		require.EqualError(err, serialErr.Error())
+	}
+}
+
+func BenchmarkGetEntitiesParallel(b *testing.B) {
+	require := require.New(b)
+	ctx := context.Background()
+
+	fs := memfs.New()
+	fp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	newTestEntities(require, fp, 10_000)
+
+	for _, tc := range []struct {
+		name    string
+		workers int
+	}{
+		{"Serial", 1},
+		{"Parallel", 0},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			p, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{Workers: tc.workers})
+			require.NoError(err, "NewFilesystemProviderWithConfig")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = p.GetEntities(ctx); err != nil {
+					b.Fatalf("GetEntities: %s", err)
+				}
+			}
+		})
+	}
+}
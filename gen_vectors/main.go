@@ -1,9 +1,11 @@
-// gen_vectors generates test vectors for entity metadata descriptors.
+// gen_vectors generates test vectors for entity and runtime metadata descriptors.
 package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"os"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
@@ -12,16 +14,17 @@ import (
 	"github.com/oasisprotocol/metadata-registry-tools/testvectors"
 )
 
-func main() {
-	// Configure chain context for all signatures using chain domain separation.
-	var chainContext hash.Hash
-	chainContext.FromBytes([]byte("metadata registry test vectors"))
-	signature.SetChainContext(chainContext.String())
+const (
+	kindEntity  = "entity"
+	kindRuntime = "runtime"
+)
 
+func entityVectors() []testvectors.EntityMetadataTestVector {
 	vectors := make(
 		[]testvectors.EntityMetadataTestVector,
 		0,
-		len(testcases.EntityMetadataBasicVersionAndSize)+len(testcases.EntityMetadataExtendedVersionAndSize),
+		len(testcases.EntityMetadataBasicVersionAndSize)+len(testcases.EntityMetadataExtendedVersionAndSize)+
+			len(testcases.EntityMetadataV2BasicVersionAndSize)+len(testcases.EntityMetadataV2ExtendedVersionAndSize),
 	)
 
 	for _, tc := range testcases.EntityMetadataBasicVersionAndSize {
@@ -40,7 +43,72 @@ func main() {
 		vectors = append(vectors, vec)
 	}
 
+	for _, tc := range testcases.EntityMetadataV2BasicVersionAndSize {
+		tc := tc
+		vec := testvectors.MakeEntityMetadataTestVector(
+			"EntityMetadataV2BasicVersionAndSize", &tc.EntityMeta, tc.Valid,
+		)
+		vectors = append(vectors, vec)
+	}
+
+	for _, tc := range testcases.EntityMetadataV2ExtendedVersionAndSize {
+		tc := tc
+		vec := testvectors.MakeEntityMetadataTestVector(
+			"EntityMetadataV2ExtendedVersionAndSize", &tc.EntityMeta, tc.Valid,
+		)
+		vectors = append(vectors, vec)
+	}
+
+	return vectors
+}
+
+func runtimeVectors() []testvectors.RuntimeMetadataTestVector {
+	vectors := make(
+		[]testvectors.RuntimeMetadataTestVector,
+		0,
+		len(testcases.RuntimeMetadataBasicVersionAndSize)+len(testcases.RuntimeMetadataExtendedVersionAndSize),
+	)
+
+	for _, tc := range testcases.RuntimeMetadataBasicVersionAndSize {
+		tc := tc
+		vec := testvectors.MakeRuntimeMetadataTestVector(
+			"RuntimeMetadataBasicVersionAndSize", &tc.RuntimeMeta, tc.Valid,
+		)
+		vectors = append(vectors, vec)
+	}
+
+	for _, tc := range testcases.RuntimeMetadataExtendedVersionAndSize {
+		tc := tc
+		vec := testvectors.MakeRuntimeMetadataTestVector(
+			"RuntimeMetadataExtendedVersionAndSize", &tc.RuntimeMeta, tc.Valid,
+		)
+		vectors = append(vectors, vec)
+	}
+
+	return vectors
+}
+
+func main() {
+	kind := flag.String("kind", kindEntity, "kind of test vectors to generate [entity,runtime]")
+	flag.Parse()
+
+	// Configure chain context for all signatures using chain domain separation.
+	var chainContext hash.Hash
+	chainContext.FromBytes([]byte("metadata registry test vectors"))
+	signature.SetChainContext(chainContext.String())
+
+	var vectors interface{}
+	switch *kind {
+	case kindEntity:
+		vectors = entityVectors()
+	case kindRuntime:
+		vectors = runtimeVectors()
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported test vector kind: %s\n", *kind)
+		os.Exit(1)
+	}
+
 	// Generate output.
-	jsonOut, _ := json.MarshalIndent(&vectors, "", "  ")
+	jsonOut, _ := json.MarshalIndent(vectors, "", "  ")
 	fmt.Printf("%s", jsonOut)
 }
@@ -1,12 +1,50 @@
 package registry
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+)
+
+const (
+	gitRemoteName = "origin"
+
+	// gitShallowDepth is the depth of fetches when the full history is not required.
+	gitShallowDepth = 1
+	// gitUnshallowDepth is the depth of fetches converting a shallow repository to a complete one
+	// (the same depth as used by git fetch --unshallow).
+	gitUnshallowDepth = math.MaxInt32
+	// gitMaxDeepenDepth is the maximum depth a shallow repository is deepened to when checking
+	// that an update fast-forwards the previously fetched branch.
+	gitMaxDeepenDepth = 1024
+)
+
+var (
+	// ErrNoSuchRevision is the error returned where the requested revision cannot be found.
+	ErrNoSuchRevision = errors.New("registry/git: no such revision")
+
+	// ErrNotFastForward is the error returned where the upstream branch has been updated in a way
+	// which does not fast-forward the previously fetched branch (e.g. by a force-push).
+	ErrNotFastForward = errors.New("registry/git: update is not a fast-forward")
 )
 
 // GitConfig contains the configuration of the Git provider.
@@ -16,6 +54,33 @@ type GitConfig struct {
 
 	// Branch is the Git branch to use.
 	Branch string
+
+	// Commit is the hash of the commit to use instead of the HEAD of Branch. When set, the full
+	// history of Branch (or of all branches in case Branch is empty) is fetched in order to find
+	// the commit.
+	Commit string
+
+	// Tag is the Git tag to use instead of the HEAD of Branch.
+	Tag string
+
+	// FullHistory configures whether the full history is fetched, which enables historical
+	// lookups. Otherwise only the HEAD commit is fetched and, on refresh, the history is deepened
+	// as far as needed (but at most 1024 commits) to check that the update fast-forwards the
+	// previously fetched branch.
+	FullHistory bool
+
+	// RefreshInterval is the interval at which the provider refreshes the registry in the
+	// background. Zero disables background refreshing.
+	RefreshInterval time.Duration
+
+	// Workers is the maximum number of statements loaded and verified in parallel. If zero, the
+	// number of CPUs usable by the process (GOMAXPROCS) is used.
+	Workers int
+
+	// CacheDir is the directory where a bare clone of the repository is kept between restarts.
+	// In case the remote is unreachable, the last fetched registry is used instead. When empty,
+	// the repository is only kept in memory.
+	CacheDir string
 }
 
 // NewGitConfig creates a default Git provider configuration pointing to the production branch.
@@ -34,18 +99,971 @@ func NewTestGitConfig() GitConfig {
 	}
 }
 
+// GitProvider is a Git-backed registry provider which can be refreshed.
+type GitProvider interface {
+	StatementProvider
+
+	// Refresh fetches the configured branch and, in case its HEAD commit changed, atomically
+	// switches to the updated registry. Updates which do not fast-forward the branch are rejected
+	// with ErrNotFastForward and the previously fetched registry continues to be served.
+	Refresh(ctx context.Context) error
+
+	// WatchUpdates returns a channel that receives the new HEAD commit hash each time the
+	// registry is updated.
+	WatchUpdates() (<-chan string, pubsub.ClosableSubscription)
+
+	// Revision returns the hash of the commit being served.
+	Revision() string
+
+	// GetEntitiesAt returns a list of all entities in the registry as of the given revision.
+	//
+	// Historical lookups require GitConfig.FullHistory to be set.
+	GetEntitiesAt(ctx context.Context, rev GitRevision) (map[signature.PublicKey]*EntityMetadata, error)
+
+	// GetEntityAt returns metadata for a specific entity as of the given revision.
+	//
+	// Historical lookups require GitConfig.FullHistory to be set.
+	GetEntityAt(ctx context.Context, id signature.PublicKey, rev GitRevision) (*EntityMetadata, error)
+
+	// GetEntityHistory returns all versions of the metadata for a specific entity, ordered from
+	// the oldest to the newest.
+	//
+	// Historical lookups require GitConfig.FullHistory to be set.
+	GetEntityHistory(ctx context.Context, id signature.PublicKey) ([]*EntityHistoryRecord, error)
+
+	// State returns the state of the registry snapshot currently being served.
+	State() GitState
+
+	// Stop stops the background refresh (if enabled).
+	Stop()
+}
+
+// GitState describes the registry snapshot served by a Git provider.
+type GitState struct {
+	// Revision is the hash of the commit being served.
+	Revision string
+
+	// CommitTime is the committer time of the commit being served.
+	CommitTime time.Time
+
+	// FetchedAt is the time of the last successful fetch from the remote. It is zero in case the
+	// remote has not been reached since the provider was created.
+	FetchedAt time.Time
+
+	// Stale is true when the last fetch failed and a previously fetched registry is served.
+	Stale bool
+
+	// FetchError is the error encountered during the last fetch (if any).
+	FetchError error
+}
+
+// Age returns the time elapsed since the commit being served was made.
+func (s *GitState) Age() time.Duration {
+	return time.Since(s.CommitTime)
+}
+
+// GitRevision identifies a point in the registry history either by commit or by time.
+type GitRevision struct {
+	// Commit is the commit hash (or any other revision understood by Git, e.g. a tag name).
+	Commit string
+
+	// Time selects the last commit made at or before the given time.
+	Time time.Time
+}
+
+// GitRevisionAtCommit returns a revision referring to the given commit.
+func GitRevisionAtCommit(commit string) GitRevision {
+	return GitRevision{Commit: commit}
+}
+
+// GitRevisionAtTime returns a revision referring to the last commit made at or before the given
+// time.
+func GitRevisionAtTime(t time.Time) GitRevision {
+	return GitRevision{Time: t}
+}
+
+// String returns a string representation of the revision.
+func (r GitRevision) String() string {
+	if r.Commit != "" {
+		return r.Commit
+	}
+	return r.Time.Format(time.RFC3339)
+}
+
+// EntityHistoryRecord is a version of entity metadata found in the registry history.
+type EntityHistoryRecord struct {
+	// Commit is the hash of the commit that introduced this version.
+	Commit string
+
+	// Time is the author time of the commit that introduced this version.
+	Time time.Time
+
+	// Serial is the serial number of the entity metadata statement.
+	Serial uint64
+
+	// Metadata is the entity metadata.
+	Metadata *EntityMetadata
+}
+
+var (
+	_ GitProvider      = (*gitProvider)(nil)
+	_ SnapshotProvider = (*gitProvider)(nil)
+)
+
+type gitProvider struct {
+	sync.RWMutex
+
+	cfg  GitConfig
+	repo *git.Repository
+
+	// repoLock serializes access to the underlying repository.
+	repoLock sync.Mutex
+	state    GitState
+	current  *fsProvider
+
+	updates *pubsub.Broker
+
+	ctx      context.Context
+	cancelFn context.CancelFunc
+	quitCh   chan struct{}
+
+	logger *logging.Logger
+}
+
+// head returns the provider serving the commit currently being served.
+func (p *gitProvider) head() *fsProvider {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.current
+}
+
+// Implements Provider.
+func (p *gitProvider) Verify() error {
+	return p.head().Verify()
+}
+
+// Implements Provider.
+func (p *gitProvider) VerifyUpdate(src Provider) error {
+	return p.head().VerifyUpdate(src)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) VerifyContext(ctx context.Context) error {
+	return p.head().VerifyContext(ctx)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) VerifyUpdateContext(ctx context.Context, src Provider) error {
+	return p.head().VerifyUpdateContext(ctx, src)
+}
+
+// Implements Provider.
+func (p *gitProvider) GetEntities(ctx context.Context) (map[signature.PublicKey]*EntityMetadata, error) {
+	return p.head().GetEntities(ctx)
+}
+
+// Implements Provider.
+func (p *gitProvider) GetEntity(ctx context.Context, id signature.PublicKey) (*EntityMetadata, error) {
+	return p.head().GetEntity(ctx, id)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetNodes(ctx context.Context) (map[signature.PublicKey]*NodeMetadata, error) {
+	return p.head().GetNodes(ctx)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetNode(ctx context.Context, id signature.PublicKey) (*NodeMetadata, error) {
+	return p.head().GetNode(ctx, id)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetRuntimes(ctx context.Context) (map[common.Namespace]*RuntimeMetadata, error) {
+	return p.head().GetRuntimes(ctx)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetRuntime(ctx context.Context, id common.Namespace) (*RuntimeMetadata, error) {
+	return p.head().GetRuntime(ctx, id)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetStatements(ctx context.Context, kind *StatementKind) (map[StatementID]Statement, error) {
+	return p.head().GetStatements(ctx, kind)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetStatement(ctx context.Context, kind *StatementKind, id StatementID) (Statement, error) {
+	return p.head().GetStatement(ctx, kind, id)
+}
+
+// Implements StatementSource.
+func (p *gitProvider) GetRawStatements(ctx context.Context, kind *StatementKind) (map[string][]byte, error) {
+	return p.head().GetRawStatements(ctx, kind)
+}
+
+// Implements StatementSource.
+func (p *gitProvider) GetRawStatement(ctx context.Context, kind *StatementKind, id StatementID) ([]byte, error) {
+	return p.head().GetRawStatement(ctx, kind, id)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetSignedStatement(
+	ctx context.Context,
+	kind *StatementKind,
+	id StatementID,
+) (*SignedStatement, error) {
+	return p.head().GetSignedStatement(ctx, kind, id)
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) GetSignedStatements(
+	ctx context.Context,
+	kind *StatementKind,
+) (map[StatementID]*SignedStatement, error) {
+	return p.head().GetSignedStatements(ctx, kind)
+}
+
+// Implements SnapshotProvider.
+func (p *gitProvider) Snapshot(context.Context) (StatementProvider, error) {
+	return p.head(), nil
+}
+
+// Implements snapshotSource.
+func (p *gitProvider) snapshot(context.Context) (StatementSource, error) {
+	return p.head(), nil
+}
+
+// Implements GitProvider.
+func (p *gitProvider) Refresh(ctx context.Context) error {
+	if err := p.refresh(ctx); err != nil {
+		return fmt.Errorf("registry/git: failed to refresh: %w", err)
+	}
+	return nil
+}
+
+func (p *gitProvider) refresh(ctx context.Context) error {
+	p.repoLock.Lock()
+	defer p.repoLock.Unlock()
+
+	// A pinned commit never changes, so there is nothing to refresh once it has been loaded.
+	if p.cfg.Commit != "" && p.head() != nil {
+		return nil
+	}
+
+	if err := p.fetch(ctx); err != nil {
+		return err
+	}
+	return p.update()
+}
+
+func (p *gitProvider) fetch(ctx context.Context) error {
+	err := p.fetchRevision(ctx)
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.state.Stale = err != nil
+	p.state.FetchError = err
+	if err == nil {
+		p.state.FetchedAt = time.Now()
+	}
+	return err
+}
+
+// fetchRevision fetches the configured revision and, when following a branch, checks that the
+// update fast-forwards the previously fetched branch.
+func (p *gitProvider) fetchRevision(ctx context.Context) error {
+	var prev plumbing.Hash
+	if p.cfg.Commit == "" && p.cfg.Tag == "" {
+		// The branch may not have been fetched yet.
+		prev, _ = p.resolveHash()
+	}
+
+	err := p.repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: gitRemoteName,
+		Depth:      p.fetchDepth(),
+	})
+	switch {
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+	default:
+		return fmt.Errorf("failed to fetch repository: %w", err)
+	}
+
+	if prev.IsZero() {
+		return nil
+	}
+	return p.checkFastForward(ctx, prev)
+}
+
+// fetchDepth returns the depth of fetches from the remote.
+func (p *gitProvider) fetchDepth() int {
+	if !p.cfg.FullHistory && p.cfg.Commit == "" {
+		return gitShallowDepth
+	}
+
+	// A cached repository may have been fetched shallowly, in which case fetching without a depth
+	// does not fetch the missing history.
+	if shallow, _ := p.repo.Storer.Shallow(); len(shallow) > 0 {
+		return gitUnshallowDepth
+	}
+	return 0
+}
+
+// checkFastForward checks that the fetched branch fast-forwards the previously fetched commit
+// prev. A shallow history is deepened in bounded steps until prev is found. In case the update
+// is not a fast-forward, the branch is reset to prev.
+func (p *gitProvider) checkFastForward(ctx context.Context, prev plumbing.Hash) error {
+	head, err := p.resolveHash()
+	if err != nil {
+		return err
+	}
+
+	for depth := gitShallowDepth; ; {
+		found, complete, err := isGitAncestor(p.repo, prev, head)
+		if err != nil {
+			return fmt.Errorf("failed to walk history: %w", err)
+		}
+		if found {
+			return nil
+		}
+		if complete || depth >= gitMaxDeepenDepth {
+			break
+		}
+
+		depth = min(2*depth, gitMaxDeepenDepth)
+		err = p.repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: gitRemoteName,
+			Depth:      depth,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return fmt.Errorf("failed to deepen repository: %w", err)
+		}
+	}
+
+	ref := plumbing.NewHashReference(plumbing.NewRemoteReferenceName(gitRemoteName, p.cfg.Branch), prev)
+	if err = p.repo.Storer.SetReference(ref); err != nil {
+		return fmt.Errorf("failed to reset branch '%s': %w", p.cfg.Branch, err)
+	}
+	return fmt.Errorf("%w: branch '%s'", ErrNotFastForward, p.cfg.Branch)
+}
+
+// isGitAncestor returns true iff commit ancestor is reachable from commit head. It also returns
+// whether the history of head is complete, i.e. the walk did not reach a shallow boundary.
+func isGitAncestor(repo *git.Repository, ancestor, head plumbing.Hash) (found, complete bool, err error) {
+	complete = true
+	seen := map[plumbing.Hash]bool{head: true}
+	queue := []plumbing.Hash{head}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if hash == ancestor {
+			return true, complete, nil
+		}
+
+		commit, err := repo.CommitObject(hash)
+		switch {
+		case err == nil:
+		case errors.Is(err, plumbing.ErrObjectNotFound):
+			complete = false
+			continue
+		default:
+			return false, false, err
+		}
+		for _, parent := range commit.ParentHashes {
+			if !seen[parent] {
+				seen[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return false, complete, nil
+}
+
+// resolveHash resolves the configured revision to a commit hash.
+func (p *gitProvider) resolveHash() (plumbing.Hash, error) {
+	switch {
+	case p.cfg.Commit != "":
+		return plumbing.NewHash(p.cfg.Commit), nil
+	case p.cfg.Tag != "":
+		ref, err := p.repo.Reference(plumbing.NewTagReferenceName(p.cfg.Tag), true)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to resolve tag '%s': %w", p.cfg.Tag, err)
+		}
+
+		// Peel annotated tags.
+		tag, err := p.repo.TagObject(ref.Hash())
+		switch {
+		case err == nil:
+			commit, err := tag.Commit()
+			if err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("failed to resolve tag '%s': %w", p.cfg.Tag, err)
+			}
+			return commit.Hash, nil
+		case errors.Is(err, plumbing.ErrObjectNotFound):
+			return ref.Hash(), nil
+		default:
+			return plumbing.ZeroHash, fmt.Errorf("failed to resolve tag '%s': %w", p.cfg.Tag, err)
+		}
+	default:
+		ref, err := p.repo.Reference(plumbing.NewRemoteReferenceName(gitRemoteName, p.cfg.Branch), true)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to resolve branch '%s': %w", p.cfg.Branch, err)
+		}
+		return ref.Hash(), nil
+	}
+}
+
+func (p *gitProvider) update() error {
+	hash, err := p.resolveHash()
+	if err != nil {
+		return err
+	}
+	revision := hash.String()
+	if revision == p.Revision() {
+		return nil
+	}
+
+	commit, err := p.repo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	fs, err := newCommitFilesystem(commit)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	initial := p.current == nil
+	p.state.Revision = revision
+	p.state.CommitTime = commit.Committer.When
+	p.current = &fsProvider{fs: fs, workers: p.cfg.Workers, revision: revision}
+	p.Unlock()
+
+	if !initial {
+		p.updates.Broadcast(revision)
+	}
+
+	return nil
+}
+
+// resolveRevision resolves the given revision to a commit. The caller must hold repoLock.
+func (p *gitProvider) resolveRevision(rev GitRevision) (*object.Commit, error) {
+	if !p.cfg.FullHistory {
+		return nil, fmt.Errorf("registry/git: historical lookups require full history")
+	}
+
+	if rev.Commit != "" {
+		hash, err := p.repo.ResolveRevision(plumbing.Revision(rev.Commit))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoSuchRevision, rev)
+		}
+		return p.repo.CommitObject(*hash)
+	}
+
+	iter, err := p.repo.Log(&git.LogOptions{
+		From:  plumbing.NewHash(p.Revision()),
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, gitHistoryError(err)
+	}
+	defer iter.Close()
+
+	var commit *object.Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Committer.When.After(rev.Time) {
+			return nil
+		}
+		commit = c
+		return storer.ErrStop
+	})
+	if err != nil {
+		return nil, gitHistoryError(err)
+	}
+	if commit == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchRevision, rev)
+	}
+	return commit, nil
+}
+
+// gitHistoryError wraps an error encountered while walking the history. Walks fail on missing
+// commits in case the history is incomplete (e.g. fetched shallowly), which is reported as such
+// instead of as a generic error.
+func gitHistoryError(err error) error {
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return fmt.Errorf("registry/git: history is incomplete: %w", err)
+	}
+	return fmt.Errorf("registry/git: failed to walk history: %w", err)
+}
+
+// Implements GitProvider.
+func (p *gitProvider) GetEntitiesAt(
+	ctx context.Context,
+	rev GitRevision,
+) (map[signature.PublicKey]*EntityMetadata, error) {
+	p.repoLock.Lock()
+	defer p.repoLock.Unlock()
+
+	commit, err := p.resolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := newCommitFilesystem(commit)
+	if err != nil {
+		return nil, fmt.Errorf("registry/git: %w", err)
+	}
+	return (&fsProvider{fs: fs, workers: p.cfg.Workers}).GetEntities(ctx)
+}
+
+// Implements GitProvider.
+func (p *gitProvider) GetEntityAt(
+	ctx context.Context,
+	id signature.PublicKey,
+	rev GitRevision,
+) (*EntityMetadata, error) {
+	p.repoLock.Lock()
+	defer p.repoLock.Unlock()
+
+	commit, err := p.resolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkEntityRevoked(loadCommitEntity(commit, id))
+}
+
+// loadCommitEntity loads and verifies entity metadata as of the given commit.
+func loadCommitEntity(commit *object.Commit, id signature.PublicKey) (*EntityMetadata, error) {
+	f, err := commit.File(gitEntityPath(id))
+	switch {
+	case err == nil:
+	case errors.Is(err, object.ErrFileNotFound):
+		return nil, ErrNoSuchEntity
+	default:
+		return nil, fmt.Errorf("%w: failed to open entity metadata: %s", ErrCorruptedRegistry, err)
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open entity metadata: %s", ErrCorruptedRegistry, err)
+	}
+	defer r.Close()
+
+	entity := new(EntityMetadata)
+	return entity, entity.Load(id, r)
+}
+
+// Implements GitProvider.
+func (p *gitProvider) GetEntityHistory(ctx context.Context, id signature.PublicKey) ([]*EntityHistoryRecord, error) {
+	p.repoLock.Lock()
+	defer p.repoLock.Unlock()
+
+	if !p.cfg.FullHistory {
+		return nil, fmt.Errorf("registry/git: historical lookups require full history")
+	}
+
+	return gitEntityHistory(ctx, p.repo, plumbing.NewHash(p.Revision()), id)
+}
+
+// gitEntityHistory returns all versions of the metadata for a specific entity found in the
+// history of the given commit, ordered from the oldest to the newest.
+func gitEntityHistory(
+	ctx context.Context,
+	repo *git.Repository,
+	from plumbing.Hash,
+	id signature.PublicKey,
+) ([]*EntityHistoryRecord, error) {
+	entityPath := gitEntityPath(id)
+	iter, err := repo.Log(&git.LogOptions{
+		From:     from,
+		Order:    git.LogOrderCommitterTime,
+		FileName: &entityPath,
+	})
+	if err != nil {
+		return nil, gitHistoryError(err)
+	}
+	defer iter.Close()
+
+	var history []*EntityHistoryRecord
+	err = iter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entity, err := loadCommitEntity(c, id)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrNoSuchEntity):
+			// Statement has been removed in this commit.
+			return nil
+		default:
+			return fmt.Errorf("commit %s: %w", c.Hash, err)
+		}
+
+		history = append(history, &EntityHistoryRecord{
+			Commit:   c.Hash.String(),
+			Time:     c.Author.When,
+			Serial:   entity.Serial,
+			Metadata: entity,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, gitHistoryError(err)
+	}
+
+	// Order from the oldest to the newest version, skipping commits which did not change the
+	// statement (e.g. merges).
+	records := make([]*EntityHistoryRecord, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		if n := len(records); n > 0 && records[n-1].Metadata.Equal(history[i].Metadata) {
+			continue
+		}
+		records = append(records, history[i])
+	}
+	return records, nil
+}
+
+// Implements StatementProvider.
+func (p *gitProvider) Revision() string {
+	return p.State().Revision
+}
+
+// Implements GitProvider.
+func (p *gitProvider) State() GitState {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.state
+}
+
+// Implements GitProvider.
+func (p *gitProvider) WatchUpdates() (<-chan string, pubsub.ClosableSubscription) {
+	typedCh := make(chan string)
+	sub := p.updates.Subscribe()
+	sub.Unwrap(typedCh)
+	return typedCh, sub
+}
+
+// Implements GitProvider.
+func (p *gitProvider) Stop() {
+	p.cancelFn()
+	<-p.quitCh
+}
+
+func (p *gitProvider) worker() {
+	defer close(p.quitCh)
+
+	if p.cfg.RefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.Refresh(p.ctx); err != nil {
+			p.logger.Error("failed to refresh registry",
+				"err", err,
+			)
+		}
+	}
+}
+
+// gitEntityPath returns the path of the given entity's statement within the repository.
+func gitEntityPath(id signature.PublicKey) string {
+	return EntityStatementKind.Path(id)
+}
+
+// newCommitFilesystem creates an in-memory filesystem containing the registry directory tree of
+// the given commit.
+func newCommitFilesystem(commit *object.Commit) (billy.Filesystem, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of commit %s: %w", commit.Hash, err)
+	}
+
+	fs := memfs.New()
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !strings.HasPrefix(f.Name, registryDir+"/") || !f.Mode.IsFile() {
+			return nil
+		}
+
+		r, err := f.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		defer r.Close()
+
+		w, err := fs.Create(f.Name)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.Name, err)
+		}
+		defer w.Close()
+
+		if _, err = io.Copy(w, r); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", f.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkout commit %s: %w", commit.Hash, err)
+	}
+	return fs, nil
+}
+
+func openGitRepository(cfg GitConfig) (*git.Repository, error) {
+	if cfg.CacheDir == "" {
+		return git.Init(memory.NewStorage(), nil)
+	}
+
+	repo, err := git.PlainOpen(cfg.CacheDir)
+	switch {
+	case err == nil:
+	case errors.Is(err, git.ErrRepositoryNotExists):
+		return git.PlainInit(cfg.CacheDir, true)
+	default:
+		return nil, err
+	}
+
+	// Make sure the cached repository fetches from the configured remote.
+	if err = repo.DeleteRemote(gitRemoteName); err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// gitRefSpec returns the refspec used to fetch the configured revision. Fetched branches are
+// force-updated, as go-git cannot check shallow updates for fast-forwards. Instead, a rewritten
+// upstream history is rejected by checkFastForward.
+func gitRefSpec(cfg GitConfig) config.RefSpec {
+	switch {
+	case cfg.Tag != "":
+		return config.RefSpec(fmt.Sprintf("refs/tags/%[1]s:refs/tags/%[1]s", cfg.Tag))
+	case cfg.Commit != "" && cfg.Branch == "":
+		return config.RefSpec(fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", gitRemoteName))
+	default:
+		return config.RefSpec(fmt.Sprintf("+refs/heads/%[1]s:refs/remotes/%[2]s/%[1]s", cfg.Branch, gitRemoteName))
+	}
+}
+
 // NewGitProvider creates a new git-backed metadata registry provider.
+//
+// The returned provider implements GitProvider.
 func NewGitProvider(cfg GitConfig) (Provider, error) {
-	fs := memfs.New()
-	_, err := git.Clone(memory.NewStorage(), fs, &git.CloneOptions{
-		URL:           cfg.URL,
-		Depth:         1,
-		ReferenceName: plumbing.NewBranchReferenceName(cfg.Branch),
-		SingleBranch:  true,
-		Tags:          git.NoTags,
+	if cfg.Commit != "" && cfg.Tag != "" {
+		return nil, fmt.Errorf("registry/git: commit and tag are mutually exclusive")
+	}
+	if cfg.Commit != "" && !plumbing.IsHash(cfg.Commit) {
+		return nil, fmt.Errorf("registry/git: malformed commit hash '%s'", cfg.Commit)
+	}
+
+	repo, err := openGitRepository(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("registry/git: failed to initialize repository: %w", err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name:  gitRemoteName,
+		URLs:  []string{cfg.URL},
+		Fetch: []config.RefSpec{gitRefSpec(cfg)},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("registry/git: failed to clone repository: %w", err)
+		return nil, fmt.Errorf("registry/git: failed to configure remote: %w", err)
+	}
+
+	p := &gitProvider{
+		cfg:     cfg,
+		repo:    repo,
+		updates: pubsub.NewBroker(false),
+		quitCh:  make(chan struct{}),
+		logger:  logging.GetLogger("registry/git"),
+	}
+	if err = p.refresh(context.Background()); err != nil {
+		// Fall back to the cached registry (if any) in case the remote is unreachable.
+		if cfg.CacheDir == "" || p.update() != nil {
+			return nil, fmt.Errorf("registry/git: failed to clone repository: %w", err)
+		}
+
+		state := p.State()
+		p.logger.Warn("failed to fetch repository, using cached registry",
+			"err", err,
+			"revision", state.Revision,
+			"commit_time", state.CommitTime,
+		)
+	}
+
+	p.ctx, p.cancelFn = context.WithCancel(context.Background())
+	go p.worker()
+
+	return p, nil
+}
+
+// openLocalGitRepository opens the Git repository containing the given path.
+func openLocalGitRepository(path string) (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("registry/git: failed to open repository: %w", err)
+	}
+	return repo, nil
+}
+
+func resolveLocalCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchRevision, rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("registry/git: failed to get commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+func newCommitProvider(commit *object.Commit, opts *VerifyOptions) (StatementProvider, error) {
+	fs, err := newCommitFilesystem(commit)
+	if err != nil {
+		return nil, fmt.Errorf("registry/git: %w", err)
+	}
+	p := &fsProvider{fs: fs, revision: commit.Hash.String()}
+	if opts != nil {
+		p.verifyOpts = *opts
+	}
+	return p, nil
+}
+
+// NewGitRevisionProvider creates a new registry provider for the given revision of the local Git
+// repository containing path.
+func NewGitRevisionProvider(path, rev string) (StatementProvider, error) {
+	repo, err := openLocalGitRepository(path)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := resolveLocalCommit(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	return newCommitProvider(commit, nil)
+}
+
+// GetGitEntityHistory returns all versions of the metadata for a specific entity found in the
+// history of the given revision of the local Git repository containing path, ordered from the
+// oldest to the newest.
+func GetGitEntityHistory(
+	ctx context.Context,
+	path, rev string,
+	id signature.PublicKey,
+) ([]*EntityHistoryRecord, error) {
+	repo, err := openLocalGitRepository(path)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := resolveLocalCommit(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	return gitEntityHistory(ctx, repo, commit.Hash, id)
+}
+
+// VerifyGitRange verifies the integrity of every commit in the range base..head of the local Git
+// repository containing path. Each commit (reachable from head but not from base) must contain a
+// valid registry which is a valid update of the registries in all of its parent commits.
+//
+// New statements are verified using the given options, which may be nil.
+func VerifyGitRange(path, base, head string, opts *VerifyOptions) error {
+	repo, err := openLocalGitRepository(path)
+	if err != nil {
+		return err
+	}
+	baseCommit, err := resolveLocalCommit(repo, base)
+	if err != nil {
+		return err
+	}
+	headCommit, err := resolveLocalCommit(repo, head)
+	if err != nil {
+		return err
+	}
+
+	// Collect all commits reachable from base.
+	excluded := make(map[plumbing.Hash]bool)
+	err = object.NewCommitPreorderIter(baseCommit, nil, nil).ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("registry/git: failed to walk history: %w", err)
+	}
+
+	// Collect all commits in range, ordered from the oldest to the newest.
+	var commits []*object.Commit
+	err = object.NewCommitPreorderIter(headCommit, excluded, nil).ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("registry/git: failed to walk history: %w", err)
+	}
+	slices.Reverse(commits)
+
+	// Count how many times the registry of each commit is needed (once for verifying the commit
+	// itself and once for each child verified as an update of it), so that only the registries
+	// which are still needed are kept in memory.
+	uses := make(map[plumbing.Hash]int)
+	for _, c := range commits {
+		uses[c.Hash]++
+		for _, parent := range c.ParentHashes {
+			uses[parent]++
+		}
+	}
+
+	providers := make(map[plumbing.Hash]Provider)
+	getProvider := func(c *object.Commit) (Provider, error) {
+		if p, ok := providers[c.Hash]; ok {
+			return p, nil
+		}
+		p, err := newCommitProvider(c, opts)
+		if err != nil {
+			return nil, err
+		}
+		providers[c.Hash] = p
+		return p, nil
+	}
+	releaseProvider := func(h plumbing.Hash) {
+		if uses[h]--; uses[h] <= 0 {
+			delete(providers, h)
+		}
+	}
+
+	for _, c := range commits {
+		p, err := getProvider(c)
+		if err != nil {
+			return err
+		}
+		if err = p.Verify(); err != nil {
+			return fmt.Errorf("registry/git: commit %s: %w", c.Hash, err)
+		}
+
+		err = c.Parents().ForEach(func(parent *object.Commit) error {
+			src, err := getProvider(parent)
+			if err != nil {
+				return err
+			}
+			if err = p.VerifyUpdate(src); err != nil {
+				return fmt.Errorf("registry/git: commit %s: update from %s: %w", c.Hash, parent.Hash, err)
+			}
+			releaseProvider(parent.Hash)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		releaseProvider(c.Hash)
 	}
-	return NewFilesystemProvider(fs)
+	return nil
 }
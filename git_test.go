@@ -3,9 +3,17 @@ package registry
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,3 +47,535 @@ func TestGitProvider(t *testing.T) {
 	_, err = gp.GetEntity(ctx, entityID)
 	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntity should fail for corrupted payload")
 }
+
+// testGitRepo is a local Git repository containing a registry, used as a remote in tests.
+type testGitRepo struct {
+	t *testing.T
+
+	dir  string
+	repo *git.Repository
+	fp   MutableProvider
+}
+
+func newTestGitRepo(t *testing.T) *testGitRepo {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(err, "PlainInit")
+
+	p, err := NewFilesystemPathProvider(dir)
+	require.NoError(err, "NewFilesystemPathProvider")
+	fp := p.(MutableStatementProvider)
+	err = fp.Init()
+	require.NoError(err, "Init")
+
+	r := &testGitRepo{
+		t:    t,
+		dir:  dir,
+		repo: repo,
+		fp:   fp,
+	}
+	r.commit("Initialize registry", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	return r
+}
+
+func (r *testGitRepo) url() string {
+	return "file://" + r.dir
+}
+
+func (r *testGitRepo) updateEntity(signer signature.Signer, entity *EntityMetadata) {
+	require := require.New(r.t)
+
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	err = r.fp.UpdateEntity(signed)
+	require.NoError(err, "UpdateEntity")
+}
+
+func (r *testGitRepo) commit(msg string, when time.Time) plumbing.Hash {
+	require := require.New(r.t)
+
+	wt, err := r.repo.Worktree()
+	require.NoError(err, "Worktree")
+	err = wt.AddWithOptions(&git.AddOptions{All: true})
+	require.NoError(err, "Add")
+	hash, err := wt.Commit(msg, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "metadata-registry-tools test",
+			Email: "test@oasisprotocol.org",
+			When:  when,
+		},
+	})
+	require.NoError(err, "Commit")
+	return hash
+}
+
+// newTestGitProvider creates a new Git provider with the given configuration.
+func newTestGitProvider(cfg GitConfig) (GitProvider, error) {
+	p, err := NewGitProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.(GitProvider), nil
+}
+
+func TestGitProviderRefresh(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer1 := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	signer2 := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 2")
+	repo.updateEntity(signer1, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	first := repo.commit("Add entity 1", time.Now())
+
+	gp, err := newTestGitProvider(GitConfig{URL: repo.url(), Branch: "master"})
+	require.NoError(err, "NewGitProvider")
+	defer gp.Stop()
+
+	ctx := context.Background()
+	entities, err := gp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+
+	// Snapshots are identified by the commit they serve.
+	snapshot, err := gp.(SnapshotProvider).Snapshot(ctx)
+	require.NoError(err, "Snapshot")
+	require.Equal(first.String(), snapshot.Revision())
+
+	ch, sub := gp.WatchUpdates()
+	defer sub.Close()
+
+	// Refreshing without any upstream changes should not trigger an update.
+	err = gp.Refresh(ctx)
+	require.NoError(err, "Refresh")
+	select {
+	case <-ch:
+		t.Fatalf("update notification received without any upstream changes")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	repo.updateEntity(signer2, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 2"})
+	head := repo.commit("Add entity 2", time.Now())
+
+	err = gp.Refresh(ctx)
+	require.NoError(err, "Refresh")
+	select {
+	case rev := <-ch:
+		require.Equal(head.String(), rev, "update notification should contain the new HEAD commit")
+	case <-time.After(time.Second):
+		t.Fatalf("failed to receive update notification")
+	}
+
+	entities, err = gp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 2)
+	require.Equal("entity 2", entities[signer2.Public()].Name)
+
+	// Snapshots are not affected by refreshes.
+	require.Equal(first.String(), snapshot.Revision())
+	entities, err = snapshot.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+}
+
+func TestGitProviderIndex(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	head := repo.commit("Add entity 1", time.Now())
+
+	gp, err := newTestGitProvider(GitConfig{URL: repo.url(), Branch: "master"})
+	require.NoError(err, "NewGitProvider")
+	defer gp.Stop()
+
+	ctx := context.Background()
+	index, err := NewIndex(ctx, gp)
+	require.NoError(err, "NewIndex")
+	require.NoError(index.Verify(), "Verify")
+	require.Equal(head.String(), index.Revision)
+
+	entries := index.Statements[EntityStatementKind.Name]
+	require.Len(entries, 1)
+	require.EqualValues(1, entries[0].Serial)
+	data, err := gp.(StatementSource).GetRawStatement(ctx, EntityStatementKind, signer.Public())
+	require.NoError(err, "GetRawStatement")
+	require.NoError(entries[0].Verify(EntityStatementKind, data), "Verify")
+}
+
+func TestGitProviderRefreshNotFastForward(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	first := repo.commit("Add entity 1", time.Now())
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2, Name: "entity 1 updated"})
+	repo.commit("Update entity 1", time.Now())
+
+	gp, err := newTestGitProvider(GitConfig{URL: repo.url(), Branch: "master"})
+	require.NoError(err, "NewGitProvider")
+	defer gp.Stop()
+
+	// Only the HEAD commit should be fetched by default.
+	shallow, err := gp.(*gitProvider).repo.Storer.Shallow()
+	require.NoError(err, "Shallow")
+	require.NotEmpty(shallow, "repository should be shallow")
+
+	// Fast-forward updates spanning multiple commits should be accepted.
+	var head plumbing.Hash
+	for serial := uint64(3); serial <= 6; serial++ {
+		repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: serial, Name: "entity 1 updated again"})
+		head = repo.commit("Update entity 1 again", time.Now())
+	}
+	ctx := context.Background()
+	err = gp.Refresh(ctx)
+	require.NoError(err, "Refresh")
+	require.Equal(head.String(), gp.Revision())
+
+	// Rewrite the upstream history.
+	wt, err := repo.repo.Worktree()
+	require.NoError(err, "Worktree")
+	err = wt.Reset(&git.ResetOptions{Commit: first, Mode: git.HardReset})
+	require.NoError(err, "Reset")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 4, Name: "entity 1 rewritten"})
+	repo.commit("Rewrite entity 1", time.Now())
+
+	err = gp.Refresh(ctx)
+	require.ErrorIs(err, ErrNotFastForward, "Refresh should reject a rewritten history")
+	err = gp.Refresh(ctx)
+	require.ErrorIs(err, ErrNotFastForward, "Refresh should keep rejecting a rewritten history")
+	state := gp.State()
+	require.Equal(head.String(), state.Revision, "Refresh should keep serving the fetched registry")
+	require.True(state.Stale)
+	require.ErrorIs(state.FetchError, ErrNotFastForward)
+
+	entity, err := gp.GetEntity(ctx, signer.Public())
+	require.NoError(err, "GetEntity")
+	require.Equal("entity 1 updated again", entity.Name)
+}
+
+func TestGitProviderBackgroundRefresh(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	gp, err := newTestGitProvider(GitConfig{
+		URL:             repo.url(),
+		Branch:          "master",
+		RefreshInterval: 50 * time.Millisecond,
+	})
+	require.NoError(err, "NewGitProvider")
+	defer gp.Stop()
+
+	ch, sub := gp.WatchUpdates()
+	defer sub.Close()
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	head := repo.commit("Add entity 1", time.Now())
+
+	select {
+	case rev := <-ch:
+		require.Equal(head.String(), rev, "update notification should contain the new HEAD commit")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("failed to receive update notification")
+	}
+
+	_, err = gp.GetEntity(context.Background(), signer.Public())
+	require.NoError(err, "GetEntity")
+}
+
+func TestGitProviderCacheDir(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	head := repo.commit("Add entity 1", time.Now())
+
+	cfg := GitConfig{
+		URL:      repo.url(),
+		Branch:   "master",
+		CacheDir: t.TempDir(),
+	}
+	gp, err := newTestGitProvider(cfg)
+	require.NoError(err, "NewGitProvider")
+	gp.Stop()
+
+	state := gp.State()
+	require.Equal(head.String(), state.Revision)
+	require.False(state.Stale)
+	require.NoError(state.FetchError)
+	require.False(state.FetchedAt.IsZero())
+
+	// Reusing the cache should fetch updates incrementally.
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2, Name: "entity 1 updated"})
+	head = repo.commit("Update entity 1", time.Now())
+
+	gp, err = newTestGitProvider(cfg)
+	require.NoError(err, "NewGitProvider")
+	gp.Stop()
+
+	require.Equal(head.String(), gp.State().Revision)
+	entity, err := gp.GetEntity(context.Background(), signer.Public())
+	require.NoError(err, "GetEntity")
+	require.Equal("entity 1 updated", entity.Name)
+
+	// An unreachable remote should fall back to the cached registry.
+	cfg.URL = "file://" + t.TempDir() + "/missing"
+	gp, err = newTestGitProvider(cfg)
+	require.NoError(err, "NewGitProvider should fall back to the cached registry")
+	gp.Stop()
+
+	state = gp.State()
+	require.Equal(head.String(), state.Revision)
+	require.True(state.Stale)
+	require.Error(state.FetchError)
+	require.True(state.FetchedAt.IsZero())
+	entity, err = gp.GetEntity(context.Background(), signer.Public())
+	require.NoError(err, "GetEntity")
+	require.Equal("entity 1 updated", entity.Name)
+
+	// Without a cache, an unreachable remote should fail.
+	cfg.CacheDir = ""
+	_, err = NewGitProvider(cfg)
+	require.Error(err, "NewGitProvider should fail without a cache")
+}
+
+func TestGitProviderPinned(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	first := repo.commit("Add entity 1", time.Now())
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2, Name: "entity 1 updated"})
+	repo.commit("Update entity 1", time.Now())
+
+	_, err := repo.repo.CreateTag("lightweight", first, nil)
+	require.NoError(err, "CreateTag")
+	_, err = repo.repo.CreateTag("annotated", first, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "metadata-registry-tools test", When: time.Now()},
+		Message: "Annotated tag",
+	})
+	require.NoError(err, "CreateTag")
+
+	for _, cfg := range []GitConfig{
+		{URL: repo.url(), Branch: "master", Commit: first.String()},
+		{URL: repo.url(), Commit: first.String()},
+		{URL: repo.url(), Tag: "lightweight"},
+		{URL: repo.url(), Tag: "annotated"},
+	} {
+		gp, err := newTestGitProvider(cfg)
+		require.NoError(err, "NewGitProvider")
+		gp.Stop()
+
+		require.Equal(first.String(), gp.Revision(), "Revision should return the pinned commit")
+		entity, err := gp.GetEntity(context.Background(), signer.Public())
+		require.NoError(err, "GetEntity")
+		require.Equal("entity 1", entity.Name)
+
+		err = gp.Refresh(context.Background())
+		require.NoError(err, "Refresh")
+		require.Equal(first.String(), gp.Revision(), "Refresh should not move a pinned provider")
+	}
+
+	_, err = NewGitProvider(GitConfig{URL: repo.url(), Commit: first.String(), Tag: "annotated"})
+	require.Error(err, "NewGitProvider should fail when both commit and tag are set")
+	_, err = NewGitProvider(GitConfig{URL: repo.url(), Commit: "not a hash"})
+	require.Error(err, "NewGitProvider should fail with a malformed commit hash")
+	_, err = NewGitProvider(GitConfig{URL: repo.url(), Tag: "missing"})
+	require.Error(err, "NewGitProvider should fail with a missing tag")
+}
+
+func TestGitProviderTagHistory(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+
+	t1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	first := repo.commit("Add entity 1", t1)
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2, Name: "entity 1 updated"})
+	head := repo.commit("Update entity 1", t2)
+	_, err := repo.repo.CreateTag("release", head, nil)
+	require.NoError(err, "CreateTag")
+
+	ctx := context.Background()
+	cacheDir := t.TempDir()
+
+	// Tags are fetched shallowly without full history.
+	gp, err := newTestGitProvider(GitConfig{URL: repo.url(), Tag: "release", CacheDir: cacheDir})
+	require.NoError(err, "NewGitProvider")
+	gp.Stop()
+	_, err = gp.GetEntityAt(ctx, signer.Public(), GitRevisionAtTime(t1))
+	require.Error(err, "GetEntityAt should fail without full history")
+	_, err = gp.GetEntityHistory(ctx, signer.Public())
+	require.Error(err, "GetEntityHistory should fail without full history")
+
+	// With full history, historical lookups see the whole history of the tag, even when the cached
+	// repository has been fetched shallowly before.
+	for _, cfg := range []GitConfig{
+		{URL: repo.url(), Tag: "release", FullHistory: true},
+		{URL: repo.url(), Tag: "release", FullHistory: true, CacheDir: cacheDir},
+	} {
+		gp, err = newTestGitProvider(cfg)
+		require.NoError(err, "NewGitProvider")
+		gp.Stop()
+		require.Equal(head.String(), gp.Revision())
+
+		for _, rev := range []GitRevision{
+			GitRevisionAtCommit(first.String()),
+			GitRevisionAtTime(t1.Add(time.Hour)),
+		} {
+			entity, err := gp.GetEntityAt(ctx, signer.Public(), rev)
+			require.NoError(err, "GetEntityAt")
+			require.EqualValues(1, entity.Serial)
+
+			entities, err := gp.GetEntitiesAt(ctx, rev)
+			require.NoError(err, "GetEntitiesAt")
+			require.Len(entities, 1)
+		}
+		_, err = gp.GetEntitiesAt(ctx, GitRevisionAtTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)))
+		require.ErrorIs(err, ErrNoSuchRevision, "GetEntitiesAt should fail before the initial commit")
+
+		history, err := gp.GetEntityHistory(ctx, signer.Public())
+		require.NoError(err, "GetEntityHistory")
+		require.Len(history, 2, "GetEntityHistory should return the whole history of the tag")
+		require.Equal(first.String(), history[0].Commit)
+		require.Equal(head.String(), history[1].Commit)
+	}
+}
+
+func TestGitProviderHistory(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer1 := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	signer2 := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 2")
+
+	t1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+	repo.updateEntity(signer1, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	first := repo.commit("Add entity 1", t1)
+	repo.updateEntity(signer1, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2, Name: "entity 1 updated"})
+	repo.updateEntity(signer2, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 2"})
+	repo.commit("Update entity 1, add entity 2", t2)
+
+	ctx := context.Background()
+
+	gp, err := newTestGitProvider(GitConfig{URL: repo.url(), Branch: "master"})
+	require.NoError(err, "NewGitProvider")
+	gp.Stop()
+	_, err = gp.GetEntityAt(ctx, signer1.Public(), GitRevisionAtCommit(first.String()))
+	require.Error(err, "GetEntityAt should fail without full history")
+
+	gp, err = newTestGitProvider(GitConfig{URL: repo.url(), Branch: "master", FullHistory: true})
+	require.NoError(err, "NewGitProvider")
+	gp.Stop()
+
+	for _, rev := range []GitRevision{
+		GitRevisionAtCommit(first.String()),
+		GitRevisionAtTime(t1),
+		GitRevisionAtTime(t1.Add(time.Hour)),
+	} {
+		entity, err := gp.GetEntityAt(ctx, signer1.Public(), rev)
+		require.NoError(err, "GetEntityAt")
+		require.Equal("entity 1", entity.Name)
+		require.EqualValues(1, entity.Serial)
+
+		_, err = gp.GetEntityAt(ctx, signer2.Public(), rev)
+		require.Equal(ErrNoSuchEntity, err, "GetEntityAt should fail for an entity added later")
+
+		entities, err := gp.GetEntitiesAt(ctx, rev)
+		require.NoError(err, "GetEntitiesAt")
+		require.Len(entities, 1)
+	}
+
+	entity, err := gp.GetEntityAt(ctx, signer1.Public(), GitRevisionAtTime(t2.Add(time.Hour)))
+	require.NoError(err, "GetEntityAt")
+	require.Equal("entity 1 updated", entity.Name)
+	entities, err := gp.GetEntitiesAt(ctx, GitRevisionAtTime(t2))
+	require.NoError(err, "GetEntitiesAt")
+	require.Len(entities, 2)
+
+	history, err := gp.GetEntityHistory(ctx, signer1.Public())
+	require.NoError(err, "GetEntityHistory")
+	require.Len(history, 2)
+	require.Equal(first.String(), history[0].Commit)
+	require.True(t1.Equal(history[0].Time))
+	require.EqualValues(1, history[0].Serial)
+	require.Equal("entity 1", history[0].Metadata.Name)
+	require.EqualValues(2, history[1].Serial)
+	require.Equal("entity 1 updated", history[1].Metadata.Name)
+
+	history, err = gp.GetEntityHistory(ctx, signer2.Public())
+	require.NoError(err, "GetEntityHistory")
+	require.Len(history, 1)
+	require.Equal("entity 2", history[0].Metadata.Name)
+
+	history, err = gp.GetEntityHistory(ctx, signature.PublicKey{})
+	require.NoError(err, "GetEntityHistory")
+	require.Empty(history)
+
+	_, err = gp.GetEntitiesAt(ctx, GitRevisionAtTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.True(errors.Is(err, ErrNoSuchRevision), "GetEntitiesAt should fail before the initial commit")
+	_, err = gp.GetEntitiesAt(ctx, GitRevisionAtCommit("0000000000000000000000000000000000000000"))
+	require.True(errors.Is(err, ErrNoSuchRevision), "GetEntitiesAt should fail for a missing commit")
+
+	// The history can also be read from a local repository.
+	localHistory, err := GetGitEntityHistory(ctx, repo.dir, "master", signer1.Public())
+	require.NoError(err, "GetGitEntityHistory")
+	require.Len(localHistory, 2)
+	require.Equal(first.String(), localHistory[0].Commit)
+	require.Equal("entity 1 updated", localHistory[1].Metadata.Name)
+	localHistory, err = GetGitEntityHistory(ctx, repo.dir, first.String(), signer1.Public())
+	require.NoError(err, "GetGitEntityHistory")
+	require.Len(localHistory, 1)
+	_, err = GetGitEntityHistory(ctx, repo.dir, "missing", signer1.Public())
+	require.True(errors.Is(err, ErrNoSuchRevision), "GetGitEntityHistory should fail for a missing revision")
+}
+
+func TestVerifyGitRange(t *testing.T) {
+	require := require.New(t)
+
+	repo := newTestGitRepo(t)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer 1")
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 1, Name: "entity 1"})
+	base := repo.commit("Add entity 1", time.Now())
+	repo.updateEntity(signer, &EntityMetadata{Versioned: cbor.NewVersioned(1), Serial: 2, Name: "entity 1 updated"})
+	update := repo.commit("Update entity 1", time.Now())
+
+	// Remove the statement and restore it in a later commit.
+	entityPath := filepath.Join(repo.dir, gitEntityPath(signer.Public()))
+	raw, err := os.ReadFile(entityPath)
+	require.NoError(err, "ReadFile")
+	err = os.Remove(entityPath)
+	require.NoError(err, "Remove")
+	removal := repo.commit("Remove entity 1", time.Now())
+	err = os.WriteFile(entityPath, raw, 0o644)
+	require.NoError(err, "WriteFile")
+	restore := repo.commit("Restore entity 1", time.Now())
+
+	src, err := NewGitRevisionProvider(repo.dir, base.String())
+	require.NoError(err, "NewGitRevisionProvider")
+	dst, err := NewGitRevisionProvider(repo.dir, "master")
+	require.NoError(err, "NewGitRevisionProvider")
+	err = dst.VerifyUpdate(src)
+	require.NoError(err, "VerifyUpdate should succeed between the range endpoints")
+
+	err = VerifyGitRange(repo.dir, base.String(), update.String(), nil)
+	require.NoError(err, "VerifyGitRange")
+	err = VerifyGitRange(repo.dir, removal.String(), restore.String(), nil)
+	require.NoError(err, "VerifyGitRange")
+	err = VerifyGitRange(repo.dir, base.String(), "master", nil)
+	require.Error(err, "VerifyGitRange should fail when an intermediate commit removes a statement")
+	require.Contains(err.Error(), removal.String())
+
+	_, err = NewGitRevisionProvider(repo.dir, "missing")
+	require.True(errors.Is(err, ErrNoSuchRevision), "NewGitRevisionProvider should fail for a missing revision")
+}
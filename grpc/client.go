@@ -0,0 +1,213 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+// EntitiesUpdate is an update of entity metadata received from WatchEntities.
+type EntitiesUpdate struct {
+	// Revision is the registry revision the entities are served from.
+	Revision string
+
+	// Entities are the verified entities whose metadata has been added or changed since the
+	// previous update. The first update contains all entities. Entities which have revoked their
+	// metadata are included together with the revocation tombstone.
+	Entities map[signature.PublicKey]*registry.EntityMetadata
+
+	// Removed are the entities whose metadata has been removed from the registry since the
+	// previous update.
+	Removed []signature.PublicKey
+}
+
+// Client is a registry provider backed by a remote registry service.
+//
+// Statements received from the service are not trusted and are verified before they are
+// returned.
+type Client interface {
+	registry.StatementProvider
+
+	// WatchEntities returns a channel that receives updates of entity metadata. The channel is
+	// closed in case the stream fails or a received statement fails verification.
+	WatchEntities(ctx context.Context) (<-chan *EntitiesUpdate, pubsub.ClosableSubscription, error)
+}
+
+type client struct {
+	registry.StatementProvider
+
+	conn   *grpc.ClientConn
+	logger *logging.Logger
+}
+
+// revisionTimeout is the timeout of registry revision requests made by the client.
+const revisionTimeout = 30 * time.Second
+
+// callOptions are the options of all calls made by the client.
+var callOptions = []grpc.CallOption{grpc.ForceCodec(&cmnGrpc.CBORCodec{})}
+
+// Implements registry.StatementSource.
+func (c *client) GetRawStatements(ctx context.Context, kind *registry.StatementKind) (map[string][]byte, error) {
+	var (
+		rsp []*Statement
+		err error
+	)
+	switch kind {
+	case registry.EntityStatementKind:
+		err = c.conn.Invoke(ctx, methodListEntities.FullName(), nil, &rsp, callOptions...)
+	default:
+		err = c.conn.Invoke(ctx, methodListStatements.FullName(), &ListStatementsRequest{Kind: kind.Name}, &rsp,
+			callOptions...,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]byte, len(rsp))
+	for _, stmt := range rsp {
+		results[stmt.ID] = stmt.Signed
+	}
+	return results, nil
+}
+
+// Implements registry.StatementSource.
+func (c *client) GetRawStatement(
+	ctx context.Context,
+	kind *registry.StatementKind,
+	id registry.StatementID,
+) ([]byte, error) {
+	var (
+		rsp Statement
+		err error
+	)
+	switch kind {
+	case registry.EntityStatementKind:
+		err = c.conn.Invoke(ctx, methodGetEntity.FullName(), id, &rsp, callOptions...)
+	default:
+		req := &GetStatementRequest{
+			Kind: kind.Name,
+			ID:   kind.Filename(id),
+		}
+		err = c.conn.Invoke(ctx, methodGetStatement.FullName(), req, &rsp, callOptions...)
+	}
+	switch {
+	case err == nil:
+		return rsp.Signed, nil
+	case status.Code(err) == codes.NotFound:
+		return nil, kind.ErrNoSuchStatement
+	default:
+		return nil, err
+	}
+}
+
+// Implements registry.StatementSource.
+func (c *client) Revision() string {
+	ctx, cancel := context.WithTimeout(context.Background(), revisionTimeout)
+	defer cancel()
+
+	var rsp string
+	if err := c.conn.Invoke(ctx, methodGetRevision.FullName(), nil, &rsp, callOptions...); err != nil {
+		c.logger.Error("failed to get registry revision",
+			"err", err,
+		)
+		return ""
+	}
+	return rsp
+}
+
+// Implements Client.
+func (c *client) WatchEntities(ctx context.Context) (<-chan *EntitiesUpdate, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], methodWatchEntities.FullName(), callOptions...)
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(nil); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan *EntitiesUpdate)
+	go func() {
+		defer close(ch)
+
+		for {
+			var rsp StatementsUpdate
+			if serr := stream.RecvMsg(&rsp); serr != nil {
+				return
+			}
+
+			update, serr := c.openEntitiesUpdate(&rsp)
+			if serr != nil {
+				c.logger.Error("received bad entities update",
+					"err", serr,
+				)
+				return
+			}
+
+			select {
+			case ch <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
+// openEntitiesUpdate verifies all entity statements in the given update.
+func (c *client) openEntitiesUpdate(rsp *StatementsUpdate) (*EntitiesUpdate, error) {
+	kind := registry.EntityStatementKind
+	update := &EntitiesUpdate{
+		Revision: rsp.Revision,
+		Entities: make(map[signature.PublicKey]*registry.EntityMetadata, len(rsp.Statements)),
+	}
+	for _, stmt := range rsp.Statements {
+		id, err := kind.ParseFilename(stmt.ID)
+		if err != nil {
+			return nil, err
+		}
+		entity, err := kind.Load(id, bytes.NewReader(stmt.Signed))
+		if err != nil {
+			return nil, err
+		}
+		update.Entities[id.(signature.PublicKey)] = entity.(*registry.EntityMetadata)
+	}
+	for _, rawID := range rsp.Removed {
+		id, err := kind.ParseFilename(rawID)
+		if err != nil {
+			return nil, err
+		}
+		update.Removed = append(update.Removed, id.(signature.PublicKey))
+	}
+	return update, nil
+}
+
+// NewClient creates a new registry client using the given gRPC connection.
+func NewClient(conn *grpc.ClientConn) Client {
+	c := &client{
+		conn:   conn,
+		logger: logging.GetLogger("registry/grpc"),
+	}
+	c.StatementProvider = registry.NewSourceProvider(c)
+	return c
+}
@@ -0,0 +1,447 @@
+// Package grpc implements a gRPC service for accessing the metadata registry.
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+// DefaultWatchInterval is the default interval at which the service checks the registry for
+// updates while entities are being watched.
+const DefaultWatchInterval = 10 * time.Second
+
+var (
+	// serviceName is the gRPC service name.
+	serviceName = cmnGrpc.ServiceName("oasis-metadata-registry.Registry")
+
+	// methodListEntities is the ListEntities method.
+	methodListEntities = serviceName.NewMethod("ListEntities", nil)
+	// methodGetEntity is the GetEntity method.
+	methodGetEntity = serviceName.NewMethod("GetEntity", signature.PublicKey{})
+	// methodListStatements is the ListStatements method.
+	methodListStatements = serviceName.NewMethod("ListStatements", &ListStatementsRequest{})
+	// methodGetStatement is the GetStatement method.
+	methodGetStatement = serviceName.NewMethod("GetStatement", &GetStatementRequest{})
+	// methodGetRevision is the GetRevision method.
+	methodGetRevision = serviceName.NewMethod("GetRevision", nil)
+
+	// methodWatchEntities is the WatchEntities method.
+	methodWatchEntities = serviceName.NewMethod("WatchEntities", nil)
+
+	// serviceDesc is the gRPC service descriptor.
+	serviceDesc = grpc.ServiceDesc{
+		ServiceName: string(serviceName),
+		HandlerType: (*registry.StatementProvider)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: methodListEntities.ShortName(),
+				Handler:    handlerListEntities,
+			},
+			{
+				MethodName: methodGetEntity.ShortName(),
+				Handler:    handlerGetEntity,
+			},
+			{
+				MethodName: methodListStatements.ShortName(),
+				Handler:    handlerListStatements,
+			},
+			{
+				MethodName: methodGetStatement.ShortName(),
+				Handler:    handlerGetStatement,
+			},
+			{
+				MethodName: methodGetRevision.ShortName(),
+				Handler:    handlerGetRevision,
+			},
+		},
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    methodWatchEntities.ShortName(),
+				Handler:       handlerWatchEntities,
+				ServerStreams: true,
+			},
+		},
+	}
+)
+
+// Statement is an encoded signed statement served by the service.
+type Statement struct {
+	// ID is the identifier of the statement in the form used for the name of the file storing the
+	// statement in the registry (without the extension).
+	ID string `json:"id"`
+
+	// Signed is the JSON-encoded signed statement.
+	Signed []byte `json:"signed"`
+}
+
+// ListStatementsRequest is a ListStatements request.
+type ListStatementsRequest struct {
+	// Kind is the name of the statement kind.
+	Kind string `json:"kind"`
+}
+
+// GetStatementRequest is a GetStatement request.
+type GetStatementRequest struct {
+	// Kind is the name of the statement kind.
+	Kind string `json:"kind"`
+
+	// ID is the identifier of the statement in the same form as Statement.ID.
+	ID string `json:"id"`
+}
+
+// StatementsUpdate is an update of entity statements sent by WatchEntities.
+type StatementsUpdate struct {
+	// Revision is the registry revision the statements are served from.
+	Revision string `json:"revision,omitempty"`
+
+	// Statements are the entity statements which have been added or changed since the previous
+	// update. The first update contains all entity statements, including revoked ones.
+	Statements []*Statement `json:"statements"`
+
+	// Removed are the identifiers of the entity statements which have been removed from the
+	// registry since the previous update.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ServiceConfig contains the configuration of the registry service.
+type ServiceConfig struct {
+	// WatchInterval is the interval at which the registry is checked for updates while entities
+	// are being watched. If zero, DefaultWatchInterval is used.
+	WatchInterval time.Duration
+}
+
+type service struct {
+	registry.StatementProvider
+
+	watchInterval time.Duration
+	logger        *logging.Logger
+}
+
+// RegisterService registers a new registry service backed by the given provider with the given
+// gRPC server.
+//
+// Clients must use the CBOR codec, see NewClient.
+func RegisterService(server *grpc.Server, p registry.StatementProvider, cfg ServiceConfig) {
+	s := &service{
+		StatementProvider: p,
+		watchInterval:     cfg.WatchInterval,
+		logger:            logging.GetLogger("registry/grpc"),
+	}
+	if s.watchInterval == 0 {
+		s.watchInterval = DefaultWatchInterval
+	}
+	server.RegisterService(&serviceDesc, s)
+}
+
+// snapshot returns a snapshot of the registry served by the service (see registry.Snapshot).
+func (s *service) snapshot(ctx context.Context) (registry.StatementProvider, error) {
+	snapshot, err := registry.Snapshot(ctx, s.StatementProvider)
+	if err != nil {
+		s.logger.Error("failed to snapshot registry",
+			"err", err,
+		)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return snapshot, nil
+}
+
+// getRevision returns the revision of the registry served by the service.
+func (s *service) getRevision(ctx context.Context) (string, error) {
+	snapshot, err := s.snapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	return snapshot.Revision(), nil
+}
+
+// listStatements returns all encoded signed statements of the given kind, ordered by identifier.
+func (s *service) listStatements(ctx context.Context, kind *registry.StatementKind) ([]*Statement, error) {
+	return s.listProviderStatements(ctx, s.StatementProvider, kind)
+}
+
+// listProviderStatements returns all encoded signed statements of the given kind served by p (e.g.
+// a snapshot of the registry), ordered by identifier.
+func (s *service) listProviderStatements(
+	ctx context.Context,
+	p registry.StatementProvider,
+	kind *registry.StatementKind,
+) ([]*Statement, error) {
+	stmts, err := p.GetSignedStatements(ctx, kind)
+	if err != nil {
+		return nil, s.toStatusError(kind, err)
+	}
+
+	results := make([]*Statement, 0, len(stmts))
+	for id, stmt := range stmts {
+		result, err := s.encodeStatement(kind, id, stmt)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results, nil
+}
+
+// getStatement returns a specific encoded signed statement of the given kind.
+func (s *service) getStatement(
+	ctx context.Context,
+	kind *registry.StatementKind,
+	id registry.StatementID,
+) (*Statement, error) {
+	stmt, err := s.GetSignedStatement(ctx, kind, id)
+	if err != nil {
+		return nil, s.toStatusError(kind, err)
+	}
+	return s.encodeStatement(kind, id, stmt)
+}
+
+// encodeStatement encodes the given signed statement of the given kind.
+func (s *service) encodeStatement(
+	kind *registry.StatementKind,
+	id registry.StatementID,
+	stmt *registry.SignedStatement,
+) (*Statement, error) {
+	data, err := json.Marshal(stmt.Signed)
+	if err != nil {
+		return nil, s.toStatusError(kind, err)
+	}
+	return &Statement{
+		ID:     kind.Filename(id),
+		Signed: data,
+	}, nil
+}
+
+// toStatusError converts errors returned by the provider to gRPC status errors.
+func (s *service) toStatusError(kind *registry.StatementKind, err error) error {
+	if errors.Is(err, kind.ErrNoSuchStatement) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	s.logger.Error("failed to serve statements",
+		"kind", kind.Name,
+		"err", err,
+	)
+	return status.Error(codes.Internal, err.Error())
+}
+
+// statementKind returns the statement kind with the given name and decodes the statement
+// identifier (if any).
+func statementKind(name, id string) (*registry.StatementKind, registry.StatementID, error) {
+	kind, err := registry.StatementKindByName(name)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if id == "" {
+		return kind, nil, nil
+	}
+
+	stmtID, err := kind.ParseFilename(id)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "bad %s identifier: %s", kind.Name, err)
+	}
+	return kind, stmtID, nil
+}
+
+func handlerListEntities( //nolint:golint
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	if interceptor == nil {
+		return srv.(*service).listStatements(ctx, registry.EntityStatementKind)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodListEntities.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*service).listStatements(ctx, registry.EntityStatementKind)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerGetEntity( //nolint:golint
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var id signature.PublicKey
+	if err := dec(&id); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(*service).getStatement(ctx, registry.EntityStatementKind, id)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetEntity.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*service).getStatement(ctx, registry.EntityStatementKind, req.(signature.PublicKey))
+	}
+	return interceptor(ctx, id, info, handler)
+}
+
+func handlerListStatements( //nolint:golint
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var req ListStatementsRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	listStatements := func(ctx context.Context, req *ListStatementsRequest) (interface{}, error) {
+		kind, _, err := statementKind(req.Kind, "")
+		if err != nil {
+			return nil, err
+		}
+		return srv.(*service).listStatements(ctx, kind)
+	}
+	if interceptor == nil {
+		return listStatements(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodListStatements.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return listStatements(ctx, req.(*ListStatementsRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerGetStatement( //nolint:golint
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var req GetStatementRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	getStatement := func(ctx context.Context, req *GetStatementRequest) (interface{}, error) {
+		if req.ID == "" {
+			return nil, status.Error(codes.InvalidArgument, "missing statement identifier")
+		}
+		kind, id, err := statementKind(req.Kind, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return srv.(*service).getStatement(ctx, kind, id)
+	}
+	if interceptor == nil {
+		return getStatement(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetStatement.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return getStatement(ctx, req.(*GetStatementRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerGetRevision( //nolint:golint
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	if interceptor == nil {
+		return srv.(*service).getRevision(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetRevision.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*service).getRevision(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerWatchEntities(srv interface{}, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(nil); err != nil {
+		return err
+	}
+
+	s := srv.(*service)
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	var (
+		revision string
+		known    map[string][]byte
+	)
+	for {
+		// Take the revision and the entities from the same snapshot, so that each update reports
+		// the revision the entities are actually served from.
+		snapshot, err := s.snapshot(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Skip listing the entities in case the registry revision is known not to have changed.
+		if rev := snapshot.Revision(); known == nil || rev == "" || rev != revision {
+			stmts, err := s.listProviderStatements(ctx, snapshot, registry.EntityStatementKind)
+			if err != nil {
+				return err
+			}
+
+			update := &StatementsUpdate{
+				Revision:   rev,
+				Statements: []*Statement{},
+			}
+			current := make(map[string][]byte, len(stmts))
+			for _, stmt := range stmts {
+				current[stmt.ID] = stmt.Signed
+				if !bytes.Equal(known[stmt.ID], stmt.Signed) {
+					update.Statements = append(update.Statements, stmt)
+				}
+			}
+			for id := range known {
+				if _, ok := current[id]; !ok {
+					update.Removed = append(update.Removed, id)
+				}
+			}
+			sort.Strings(update.Removed)
+
+			if known == nil || len(update.Statements) > 0 || len(update.Removed) > 0 {
+				if err = stream.SendMsg(update); err != nil {
+					return err
+				}
+			}
+			revision, known = rev, current
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
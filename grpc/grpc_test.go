@@ -0,0 +1,310 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+const bufSize = 1024 * 1024
+
+// testRegistry is a test registry which serializes updates made by the test with reads made by the
+// service, as memfs is not safe for concurrent use.
+type testRegistry struct {
+	sync.RWMutex
+
+	fs  billy.Filesystem
+	fp  registry.MutableProvider
+	src registry.StatementSource
+}
+
+// Implements registry.StatementSource.
+func (r *testRegistry) GetRawStatements(ctx context.Context, kind *registry.StatementKind) (map[string][]byte, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.src.GetRawStatements(ctx, kind)
+}
+
+// Implements registry.StatementSource.
+func (r *testRegistry) GetRawStatement(
+	ctx context.Context,
+	kind *registry.StatementKind,
+	id registry.StatementID,
+) ([]byte, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.src.GetRawStatement(ctx, kind, id)
+}
+
+// Implements registry.StatementSource.
+func (r *testRegistry) Revision() string {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.src.Revision()
+}
+
+func newTestRegistry(require *require.Assertions) *testRegistry {
+	fs := memfs.New()
+	fp, err := registry.NewFilesystemProviderWithConfig(fs, registry.FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	return &testRegistry{
+		fs:  fs,
+		fp:  fp,
+		src: fp.(registry.StatementSource),
+	}
+}
+
+func updateEntity(
+	require *require.Assertions,
+	r *testRegistry,
+	signer signature.Signer,
+	meta *registry.EntityMetadata,
+) {
+	signed, err := registry.SignEntityMetadata(signer, meta)
+	require.NoError(err, "SignEntityMetadata")
+
+	r.Lock()
+	defer r.Unlock()
+	require.NoError(r.fp.UpdateEntity(signed), "UpdateEntity")
+}
+
+func removeEntity(require *require.Assertions, r *testRegistry, id signature.PublicKey) {
+	r.Lock()
+	defer r.Unlock()
+	require.NoError(r.fs.Remove(registry.EntityStatementKind.Path(id)), "Remove")
+}
+
+func receiveUpdate(require *require.Assertions, ch <-chan *EntitiesUpdate) *EntitiesUpdate {
+	select {
+	case update, ok := <-ch:
+		require.True(ok, "WatchEntities channel should not be closed")
+		return update
+	case <-time.After(10 * time.Second):
+		require.FailNow("timed out waiting for entities update")
+		return nil
+	}
+}
+
+// startTestService starts a registry service backed by the given provider and returns a client
+// connection to it, together with a function stopping the service.
+func startTestService(require *require.Assertions, p registry.StatementProvider) (*grpc.ClientConn, func()) {
+	listener := bufconn.Listen(bufSize)
+	server := grpc.NewServer()
+	RegisterService(server, p, ServiceConfig{WatchInterval: 10 * time.Millisecond})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(err, "DialContext")
+
+	return conn, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestService(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	reg := newTestRegistry(require)
+	fp := registry.NewSourceProvider(reg)
+
+	conn, stop := startTestService(require, fp)
+	defer stop()
+
+	client := NewClient(conn)
+
+	// Empty registry.
+	require.NoError(client.Verify(), "Verify")
+	require.Equal(fp.Revision(), client.Revision())
+	entities, err := client.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Empty(entities)
+	nodes, err := client.GetNodes(ctx)
+	require.NoError(err, "GetNodes")
+	require.Empty(nodes)
+	runtimes, err := client.GetRuntimes(ctx)
+	require.NoError(err, "GetRuntimes")
+	require.Empty(runtimes)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, sub, err := client.WatchEntities(watchCtx)
+	require.NoError(err, "WatchEntities")
+	defer sub.Close()
+	update := receiveUpdate(require, ch)
+	require.Empty(update.Entities)
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	meta := &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "hello world",
+	}
+	updateEntity(require, reg, signer, meta)
+
+	update = receiveUpdate(require, ch)
+	require.Equal(fp.Revision(), update.Revision)
+	require.Len(update.Entities, 1)
+	require.EqualValues(meta, update.Entities[signer.Public()])
+
+	require.Equal(fp.Revision(), client.Revision())
+	entities, err = client.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+	require.EqualValues(meta, entities[signer.Public()])
+	entity, err := client.GetEntity(ctx, signer.Public())
+	require.NoError(err, "GetEntity")
+	require.EqualValues(meta, entity)
+	signed, err := client.GetSignedStatement(ctx, registry.EntityStatementKind, signer.Public())
+	require.NoError(err, "GetSignedStatement")
+	require.Equal(signer.Public(), signed.Signed.Signature.PublicKey)
+	require.EqualValues(meta, signed.Statement)
+
+	missing := memorySigner.NewTestSigner("metadata-registry-tools missing test entity signer")
+	_, err = client.GetEntity(ctx, missing.Public())
+	require.True(errors.Is(err, registry.ErrNoSuchEntity), "GetEntity should fail for missing entities")
+	_, err = client.GetNode(ctx, missing.Public())
+	require.True(errors.Is(err, registry.ErrNoSuchNode), "GetNode should fail for missing nodes")
+	_, err = client.GetRuntime(ctx, common.Namespace{})
+	require.True(errors.Is(err, registry.ErrNoSuchRuntime), "GetRuntime should fail for missing runtimes")
+
+	// Only changed entities are sent.
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	otherMeta := &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "other world",
+	}
+	updateEntity(require, reg, other, otherMeta)
+	update = receiveUpdate(require, ch)
+	require.Len(update.Entities, 1)
+	require.EqualValues(otherMeta, update.Entities[other.Public()])
+
+	// Revoked entities are skipped when listing, but sent to watchers.
+	revocation := &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(registry.MaxSupportedVersion),
+		Serial:    2,
+		Revoked:   true,
+	}
+	updateEntity(require, reg, signer, revocation)
+	update = receiveUpdate(require, ch)
+	require.Len(update.Entities, 1)
+	require.True(update.Entities[signer.Public()].Revoked)
+
+	entities, err = client.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+	require.Contains(entities, other.Public())
+	entity, err = client.GetEntity(ctx, signer.Public())
+	require.True(errors.Is(err, registry.ErrEntityRevoked), "GetEntity should fail for revoked entities")
+	require.EqualValues(revocation, entity)
+
+	// The client verifies updates against other providers.
+	require.NoError(client.VerifyUpdate(fp), "VerifyUpdate")
+
+	// Removed entities are sent to watchers.
+	removeEntity(require, reg, other.Public())
+	update = receiveUpdate(require, ch)
+	require.Empty(update.Entities)
+	require.Equal([]signature.PublicKey{other.Public()}, update.Removed)
+}
+
+// snapshotProvider is a provider whose snapshots serve a fixed registry (or fail), which differs
+// from the registry it serves itself.
+type snapshotProvider struct {
+	sync.Mutex
+	registry.StatementProvider
+
+	snapshot registry.StatementProvider
+	err      error
+}
+
+// Implements registry.SnapshotProvider.
+func (p *snapshotProvider) Snapshot(context.Context) (registry.StatementProvider, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.snapshot, p.err
+}
+
+func TestServiceSnapshot(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	empty := newTestRegistry(require)
+	reg := newTestRegistry(require)
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	meta := &registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "hello world",
+	}
+	updateEntity(require, reg, signer, meta)
+	snapshot := registry.NewSourceProvider(reg)
+
+	p := &snapshotProvider{
+		StatementProvider: registry.NewSourceProvider(empty),
+		snapshot:          snapshot,
+	}
+	conn, stop := startTestService(require, p)
+	defer stop()
+	client := NewClient(conn)
+
+	// Watchers receive the revision together with the entities of the same snapshot.
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, sub, err := client.WatchEntities(watchCtx)
+	require.NoError(err, "WatchEntities")
+	defer sub.Close()
+	update := receiveUpdate(require, ch)
+	require.Equal(snapshot.Revision(), update.Revision)
+	require.Len(update.Entities, 1)
+	require.EqualValues(meta, update.Entities[signer.Public()])
+	require.Equal(snapshot.Revision(), client.Revision())
+
+	// Failures to snapshot the registry are returned instead of an empty revision.
+	p.Lock()
+	p.err = errors.New("snapshot failed")
+	p.Unlock()
+	var rsp string
+	err = conn.Invoke(ctx, methodGetRevision.FullName(), nil, &rsp, callOptions...)
+	require.ErrorContains(err, "snapshot failed")
+	require.Empty(client.Revision())
+
+	select {
+	case _, ok := <-ch:
+		require.False(ok, "WatchEntities channel should be closed")
+	case <-time.After(10 * time.Second):
+		require.FailNow("timed out waiting for the watch to fail")
+	}
+}
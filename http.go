@@ -0,0 +1,317 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpTimeout is the timeout of HTTP requests made by the HTTP provider.
+const httpTimeout = 30 * time.Second
+
+var (
+	// ErrNoIndex is the error returned where listing statements requires the registry index, but
+	// the index cannot be found.
+	ErrNoIndex = errors.New("registry/http: no registry index")
+
+	// ErrUnexpectedIndex is the error returned where the registry index does not match the pinned
+	// index hash (see HTTPConfig.IndexHash).
+	ErrUnexpectedIndex = errors.New("registry/http: unexpected registry index")
+)
+
+type httpSource struct {
+	baseURL   *url.URL
+	client    *http.Client
+	workers   int
+	indexHash string
+
+	// lastIndex is the most recently fetched index together with its ETag (if any), so that the
+	// index need not be downloaded again while it has not changed.
+	lastIndex struct {
+		sync.Mutex
+
+		index *Index
+		etag  string
+	}
+}
+
+// fetch fetches the resource at the given path relative to the base URL, reading at most maxSize
+// bytes. In case the resource cannot be found, errNotFound is returned.
+func (s *httpSource) fetch(ctx context.Context, path string, maxSize int64, errNotFound error) ([]byte, error) {
+	data, _, err := s.fetchIfNoneMatch(ctx, path, maxSize, errNotFound, "")
+	return data, err
+}
+
+// fetchIfNoneMatch fetches the resource like fetch, unless its ETag matches the given ETag (if not
+// empty), in which case nil data is returned. The ETag of the fetched resource is returned
+// together with the data.
+func (s *httpSource) fetchIfNoneMatch(
+	ctx context.Context,
+	path string,
+	maxSize int64,
+	errNotFound error,
+	etag string,
+) ([]byte, string, error) {
+	u := s.baseURL.JoinPath(strings.Split(path, "/")...)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("registry/http: failed to create request: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("registry/http: failed to fetch '%s': %w", u, err)
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if etag != "" {
+			return nil, etag, nil
+		}
+		return nil, "", fmt.Errorf("registry/http: failed to fetch '%s': %s", u, rsp.Status)
+	case http.StatusNotFound:
+		return nil, "", errNotFound
+	default:
+		return nil, "", fmt.Errorf("registry/http: failed to fetch '%s': %s", u, rsp.Status)
+	}
+
+	// Read one more byte than allowed so that oversized resources can be detected.
+	data, err := io.ReadAll(io.LimitReader(rsp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("registry/http: failed to read '%s': %w", u, err)
+	}
+	return data, rsp.Header.Get("ETag"), nil
+}
+
+// fetchIndex fetches the registry index. In case the server reports that the index has not
+// changed since it was last fetched, the previously fetched index is returned.
+func (s *httpSource) fetchIndex(ctx context.Context) (*Index, error) {
+	s.lastIndex.Lock()
+	last, lastETag := s.lastIndex.index, s.lastIndex.etag
+	s.lastIndex.Unlock()
+
+	data, etag, err := s.fetchIfNoneMatch(ctx, IndexFilename, MaxIndexSize, ErrNoIndex, lastETag)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return last, nil
+	}
+	if int64(len(data)) > MaxIndexSize {
+		return nil, fmt.Errorf("%w: index too big (max: %d)", ErrCorruptedRegistry, MaxIndexSize)
+	}
+
+	var index Index
+	if err = json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal index: %s", ErrCorruptedRegistry, err)
+	}
+	if err = index.Verify(); err != nil {
+		return nil, err
+	}
+	if s.indexHash != "" && index.Hash != s.indexHash {
+		return nil, fmt.Errorf("%w (expected: %s got: %s)", ErrUnexpectedIndex, s.indexHash, index.Hash)
+	}
+
+	// Indexes served without an ETag cannot be requested conditionally, so they are not kept.
+	s.lastIndex.Lock()
+	s.lastIndex.index, s.lastIndex.etag = nil, ""
+	if etag != "" {
+		s.lastIndex.index, s.lastIndex.etag = &index, etag
+	}
+	s.lastIndex.Unlock()
+
+	return &index, nil
+}
+
+// fetchSnapshot fetches the registry index and returns a snapshot of the registry described by it.
+func (s *httpSource) fetchSnapshot(ctx context.Context) (*httpSnapshot, error) {
+	index, err := s.fetchIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &httpSnapshot{
+		src:   s,
+		index: index,
+	}, nil
+}
+
+// Implements snapshotSource.
+func (s *httpSource) snapshot(ctx context.Context) (StatementSource, error) {
+	return s.fetchSnapshot(ctx)
+}
+
+// Implements StatementSource.
+func (s *httpSource) GetRawStatements(ctx context.Context, kind *StatementKind) (map[string][]byte, error) {
+	snapshot, err := s.fetchSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.GetRawStatements(ctx, kind)
+}
+
+// Implements StatementSource.
+func (s *httpSource) GetRawStatement(ctx context.Context, kind *StatementKind, id StatementID) ([]byte, error) {
+	return s.fetch(ctx, kind.Path(id), kind.maxSize(), kind.ErrNoSuchStatement)
+}
+
+// Implements StatementSource.
+//
+// The revision of an HTTP registry is the revision recorded in its index. The index is requested
+// conditionally, so it is only downloaded again if the server reports that it has changed.
+func (s *httpSource) Revision() string {
+	ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
+	defer cancel()
+
+	index, err := s.fetchIndex(ctx)
+	if err != nil {
+		return ""
+	}
+	return index.Revision
+}
+
+// httpSnapshot is a snapshot of an HTTP registry, serving only the statements listed in a single
+// copy of the registry index.
+type httpSnapshot struct {
+	src   *httpSource
+	index *Index
+}
+
+// fetchStatement fetches the given indexed statement and checks that it matches the index.
+func (s *httpSnapshot) fetchStatement(
+	ctx context.Context,
+	kind *StatementKind,
+	id StatementID,
+	entry *IndexEntry,
+) ([]byte, error) {
+	stmtPath, err := entry.StatementPath(kind, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", kind.Name, err)
+	}
+
+	// Statements listed in the index must exist, otherwise the mirror is incomplete.
+	data, err := s.src.fetch(ctx, stmtPath, kind.maxSize(), kind.ErrNoSuchStatement)
+	if errors.Is(err, kind.ErrNoSuchStatement) {
+		return nil, fmt.Errorf("%w: %s: indexed statement missing: %s", ErrCorruptedRegistry, kind.Name, entry.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = entry.Verify(kind, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", kind.Name, err)
+	}
+	return data, nil
+}
+
+// Implements StatementSource.
+func (s *httpSnapshot) GetRawStatements(ctx context.Context, kind *StatementKind) (map[string][]byte, error) {
+	entries, ok := s.index.Statements[kind.Name]
+	if !ok && !kind.Optional {
+		return nil, fmt.Errorf("%w: %s statements missing from index", ErrCorruptedRegistry, kind.Name)
+	}
+
+	// Statements are fetched in parallel.
+	data := make([][]byte, len(entries))
+	err := forEachParallel(ctx, s.src.workers, len(entries), func(i int) error {
+		entry := entries[i]
+		id, err := kind.ParseFilename(entry.ID)
+		if err != nil {
+			return newStatementError(ErrBadFilename,
+				fmt.Errorf("%w: %s: bad statement filename '%s': %s", ErrCorruptedRegistry, kind.Name, entry.ID, err),
+			)
+		}
+
+		data[i], err = s.fetchStatement(ctx, kind, id, entry)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]byte, len(entries))
+	for i, entry := range entries {
+		results[entry.ID] = data[i]
+	}
+	return results, nil
+}
+
+// Implements StatementSource.
+func (s *httpSnapshot) GetRawStatement(ctx context.Context, kind *StatementKind, id StatementID) ([]byte, error) {
+	name := kind.Filename(id)
+	entries := s.index.Statements[kind.Name]
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].ID >= name
+	})
+	if i == len(entries) || entries[i].ID != name {
+		return nil, kind.ErrNoSuchStatement
+	}
+	return s.fetchStatement(ctx, kind, id, entries[i])
+}
+
+// Implements StatementSource.
+func (s *httpSnapshot) Revision() string {
+	return s.index.Revision
+}
+
+// HTTPConfig contains the configuration of the HTTP provider.
+type HTTPConfig struct {
+	// Workers is the maximum number of statements fetched in parallel. If zero, the number of CPUs
+	// usable by the process (GOMAXPROCS) is used.
+	Workers int
+
+	// IndexHash is the expected hash of the registry index (see Index.Hash), obtained out of band
+	// from a trusted source (e.g. as printed when exporting a static mirror). If set, any other
+	// index is rejected. This is the only protection against a stale or modified mirror, as the
+	// hash contained in the index itself can be recomputed by anyone serving the mirror.
+	IndexHash string
+}
+
+// NewHTTPProvider creates a new registry provider fetching statements over HTTP(S) from the given
+// base URL, e.g. a static mirror of the registry or a registry server.
+//
+// Statements are fetched from the same paths as in the registry repository (unless the index lists
+// another path, see IndexEntry.Path), so any mirror of the repository contents can be used.
+// Listing statements additionally requires the registry index (see Index) to be served under the
+// base URL. As the transport is not trusted, all statements are verified before they are returned.
+//
+// Raw mirrors of the repository (e.g. raw GitHub content) have no index, so they only support
+// looking up specific statements (e.g. GetEntity), while listing statements (e.g. GetEntities or
+// Verify) fails with ErrNoIndex. The index served by the registry API and by static mirrors lists
+// content-addressed paths (see ContentAddressedPath), so statements can be fetched while the
+// registry is being updated.
+//
+// Each listing uses a single copy of the index, and operations spanning multiple statement kinds
+// (e.g. Verify or creating an index with NewIndex) use the same copy for all of them.
+func NewHTTPProvider(baseURL string) (StatementProvider, error) {
+	return NewHTTPProviderWithConfig(baseURL, HTTPConfig{})
+}
+
+// NewHTTPProviderWithConfig creates a new registry provider fetching statements over HTTP(S) from
+// the given base URL with the given configuration. See NewHTTPProvider for details.
+func NewHTTPProviderWithConfig(baseURL string, cfg HTTPConfig) (StatementProvider, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("registry/http: malformed base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("registry/http: unsupported base URL scheme: '%s'", u.Scheme)
+	}
+
+	return NewSourceProvider(&httpSource{
+		baseURL:   u,
+		client:    &http.Client{Timeout: httpTimeout},
+		workers:   cfg.Workers,
+		indexHash: cfg.IndexHash,
+	}), nil
+}
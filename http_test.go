@@ -0,0 +1,289 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	_, err := NewHTTPProvider("file:///tmp/registry")
+	require.Error(err, "NewHTTPProvider should fail for unsupported schemes")
+
+	// Serve a filesystem registry as a static mirror.
+	dir := t.TempDir()
+	p, err := NewFilesystemPathProvider(dir)
+	require.NoError(err, "NewFilesystemPathProvider")
+	fp := p.(MutableStatementProvider)
+	require.NoError(fp.Init(), "Init")
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	hp, err := NewHTTPProvider(srv.URL + "/")
+	require.NoError(err, "NewHTTPProvider")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "hello world",
+	}
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+
+	// Specific statements can be fetched without the index, as from raw mirrors of the registry
+	// repository (e.g. raw GitHub content).
+	fetchedEntity, err := hp.GetEntity(ctx, signer.Public())
+	require.NoError(err, "GetEntity")
+	require.EqualValues(entity, fetchedEntity)
+	missing := memorySigner.NewTestSigner("metadata-registry-tools missing test entity signer")
+	_, err = hp.GetEntity(ctx, missing.Public())
+	require.True(errors.Is(err, ErrNoSuchEntity), "GetEntity should fail for missing entities")
+
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrNoIndex), "GetEntities should fail without the index")
+	require.True(errors.Is(hp.Verify(), ErrNoIndex), "Verify should fail without the index")
+	require.Empty(hp.Revision())
+
+	writeIndex := func() *Index {
+		index, ierr := NewIndex(ctx, fp)
+		require.NoError(ierr, "NewIndex")
+		data, ierr := json.Marshal(index)
+		require.NoError(ierr, "Marshal")
+		require.NoError(os.WriteFile(filepath.Join(dir, IndexFilename), data, 0o600), "WriteFile")
+		return index
+	}
+	index := writeIndex()
+	require.Len(index.Statements[EntityStatementKind.Name], 1)
+	require.EqualValues(1, index.Statements[EntityStatementKind.Name][0].Serial)
+
+	require.NoError(hp.Verify(), "Verify")
+	require.Equal(fp.Revision(), hp.Revision())
+	entities, err := hp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+	require.EqualValues(entity, entities[signer.Public()])
+	nodes, err := hp.GetNodes(ctx)
+	require.NoError(err, "GetNodes")
+	require.Empty(nodes)
+
+	// Statements not matching the index are rejected (e.g. in case of a stale mirror).
+	entity.Serial++
+	signed, err = SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntities should fail for stale statements")
+
+	writeIndex()
+	entities, err = hp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.EqualValues(entity, entities[signer.Public()])
+
+	// Statements must match the serial number recorded in the index.
+	index = writeIndex()
+	index.Statements[EntityStatementKind.Name][0].Serial++
+	index.Hash = index.ComputeHash()
+	data, err := json.Marshal(index)
+	require.NoError(err, "Marshal")
+	require.NoError(os.WriteFile(filepath.Join(dir, IndexFilename), data, 0o600), "WriteFile")
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntities should fail for serial mismatches")
+	writeIndex()
+
+	// Statements are verified locally.
+	path := filepath.Join(dir, filepath.FromSlash(EntityStatementKind.Path(signer.Public())))
+	data, err = os.ReadFile(path)
+	require.NoError(err, "ReadFile")
+	data[len(data)-3] ^= 0x01
+	require.NoError(os.WriteFile(path, data, 0o600), "WriteFile")
+	_, err = hp.GetEntity(ctx, signer.Public())
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntity should fail for tampered statements")
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrCorruptedRegistry), "GetEntities should fail for tampered statements")
+}
+
+func TestHTTPProviderSnapshot(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	p, err := NewFilesystemPathProvider(dir)
+	require.NoError(err, "NewFilesystemPathProvider")
+	fp := p.(MutableStatementProvider)
+	require.NoError(fp.Init(), "Init")
+	newTestEntities(require, fp, 10)
+	index, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+
+	var indexFetches, indexDownloads atomic.Int64
+	files := http.FileServer(http.Dir(dir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+IndexFilename {
+			indexFetches.Add(1)
+
+			// Serve the index with an ETag, so that it can be requested conditionally.
+			data, rerr := os.ReadFile(filepath.Join(dir, IndexFilename))
+			require.NoError(rerr, "ReadFile")
+			h := sha256.Sum256(data)
+			etag := `"` + hex.EncodeToString(h[:]) + `"`
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			indexDownloads.Add(1)
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	hp, err := NewHTTPProviderWithConfig(srv.URL, HTTPConfig{Workers: 4})
+	require.NoError(err, "NewHTTPProviderWithConfig")
+
+	// Indexes of the mirror list the same statements, but not the paths they are stored at.
+	mirroredIndex := &Index{
+		Revision:   index.Revision,
+		Statements: make(map[string][]*IndexEntry),
+	}
+	for name, entries := range index.Statements {
+		mirroredEntries := make([]*IndexEntry, 0, len(entries))
+		for _, entry := range entries {
+			mirroredEntry := *entry
+			mirroredEntry.Path = ""
+			mirroredEntries = append(mirroredEntries, &mirroredEntry)
+		}
+		mirroredIndex.Statements[name] = mirroredEntries
+	}
+	mirroredIndex.Hash = mirroredIndex.ComputeHash()
+
+	// Each operation fetches the index only once.
+	for _, tc := range []struct {
+		name string
+		fn   func() error
+	}{
+		{"Verify", hp.Verify},
+		{"CachedVerify", NewCachingProvider(hp, CacheOptions{}).Verify},
+		{"VerifyUpdate", func() error { return hp.VerifyUpdate(fp) }},
+		{"GetEntities", func() error {
+			entities, gerr := hp.GetEntities(ctx)
+			require.Len(entities, 10)
+			return gerr
+		}},
+		{"NewIndex", func() error {
+			// The revision and the statements are taken from the same copy of the index.
+			mirrored, ierr := NewIndex(ctx, hp)
+			require.Equal(mirroredIndex, mirrored)
+			return ierr
+		}},
+	} {
+		indexFetches.Store(0)
+		require.NoError(tc.fn(), tc.name)
+		require.EqualValues(1, indexFetches.Load(), tc.name)
+	}
+
+	// The index is only downloaded again once it has changed.
+	indexDownloads.Store(0)
+	for i := 0; i < 3; i++ {
+		require.Equal(index.Revision, hp.Revision())
+	}
+	require.Zero(indexDownloads.Load(), "Revision should not download unchanged indexes")
+
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "new entity",
+	}
+	signer := memorySigner.NewTestSigner("metadata-registry-tools new test entity signer")
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	index, err = ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+	require.Equal(index.Revision, hp.Revision())
+	require.EqualValues(1, indexDownloads.Load())
+	entities, err := hp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 11)
+}
+
+func TestHTTPProviderPinnedIndex(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	signers := newTestEntities(require, fp, 2)
+	stale, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+
+	signed, err := SignEntityMetadata(signers[0], &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    2,
+		Name:      "updated entity 0",
+	})
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	current, err := ExportStaticPath(ctx, fp, dir)
+	require.NoError(err, "ExportStaticPath")
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	hp, err := NewHTTPProviderWithConfig(srv.URL, HTTPConfig{IndexHash: current.Hash})
+	require.NoError(err, "NewHTTPProviderWithConfig")
+	require.NoError(hp.Verify(), "Verify")
+	require.Equal(current.Revision, hp.Revision())
+
+	writeIndex := func(index *Index) {
+		data, merr := json.Marshal(index)
+		require.NoError(merr, "Marshal")
+		require.NoError(os.WriteFile(filepath.Join(dir, IndexFilename), data, 0o600), "WriteFile")
+	}
+
+	// Stale mirrors are rejected.
+	writeIndex(stale)
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrUnexpectedIndex), "GetEntities should fail for stale mirrors")
+	require.Empty(hp.Revision())
+
+	// Modified indexes are rejected, even if their hash is recomputed.
+	tampered := *current
+	tampered.Statements = map[string][]*IndexEntry{
+		EntityStatementKind.Name: current.Statements[EntityStatementKind.Name][1:],
+	}
+	tampered.Hash = tampered.ComputeHash()
+	require.NoError(tampered.Verify(), "Verify")
+	writeIndex(&tampered)
+	_, err = hp.GetEntities(ctx)
+	require.True(errors.Is(err, ErrUnexpectedIndex), "GetEntities should fail for modified indexes")
+
+	// Without a pinned hash, only the integrity of the index is checked.
+	unpinned, err := NewHTTPProvider(srv.URL)
+	require.NoError(err, "NewHTTPProvider")
+	entities, err := unpinned.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 1)
+
+	writeIndex(current)
+	entities, err = hp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Len(entities, 2)
+}
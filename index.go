@@ -0,0 +1,233 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// IndexFilename is the name of the registry index file, relative to the base directory of the
+// registry (i.e. next to the registry directory).
+const IndexFilename = "index.json"
+
+// MaxIndexSize is the maximum encoded registry index size in bytes.
+const MaxIndexSize = 64 * 1024 * 1024
+
+// Index is a listing of all statements in a registry, which allows clients to enumerate the
+// statements where directories cannot be listed (e.g. on static HTTP mirrors).
+//
+// Statements are described by their canonical encoding, i.e. the JSON encoding of the signed
+// statement as produced by SignedEntityMetadata.Save.
+type Index struct {
+	// Revision is the revision of the indexed registry.
+	Revision string `json:"revision,omitempty"`
+
+	// Statements are the indexed statements, keyed by the name of their kind. Statements of each
+	// kind are ordered by their identifiers.
+	Statements map[string][]*IndexEntry `json:"statements"`
+
+	// Hash is the hex-encoded hash of the index (see ComputeHash), which allows clients to detect
+	// truncated or corrupted indexes. As it is not keyed, anyone can recompute it for a stale or
+	// modified index, so it only protects against such indexes when it is pinned by the client
+	// after being obtained from a trusted source (see HTTPConfig.IndexHash).
+	Hash string `json:"hash"`
+}
+
+// ComputeHash computes the hash of the index, i.e. the SHA-256 hash of the CBOR-encoded index
+// with an empty Hash field.
+func (idx *Index) ComputeHash() string {
+	unhashed := *idx
+	unhashed.Hash = ""
+	h := sha256.Sum256(cbor.Marshal(&unhashed))
+	return hex.EncodeToString(h[:])
+}
+
+// Verify checks the integrity of the index.
+func (idx *Index) Verify() error {
+	if hash := idx.ComputeHash(); idx.Hash != hash {
+		return fmt.Errorf("%w: index hash mismatch (expected: %s got: %s)", ErrCorruptedRegistry, idx.Hash, hash)
+	}
+	for name, entries := range idx.Statements {
+		for i := 1; i < len(entries); i++ {
+			if entries[i-1].ID >= entries[i].ID {
+				return fmt.Errorf("%w: %s statements in index not ordered: %s", ErrCorruptedRegistry, name, entries[i].ID)
+			}
+		}
+	}
+	return nil
+}
+
+// IndexEntry describes a single statement in the registry index.
+type IndexEntry struct {
+	// ID is the identifier of the statement in the form used for the name of the file storing the
+	// statement (without the extension).
+	ID string `json:"id"`
+
+	// Serial is the serial number of the statement.
+	Serial uint64 `json:"serial"`
+
+	// Size is the size of the encoded signed statement in bytes.
+	Size int64 `json:"size"`
+
+	// SHA256 is the hex-encoded SHA-256 hash of the encoded signed statement.
+	SHA256 string `json:"sha256"`
+
+	// Path is the path of the encoded signed statement relative to the registry base directory.
+	// When empty, the statement is stored at the same path as in the registry repository (see
+	// StatementKind.Path).
+	Path string `json:"path,omitempty"`
+}
+
+// StatementPath returns the path of the encoded signed statement of the given kind relative to the
+// registry base directory. Paths outside of the directory of the statement kind are rejected.
+func (e *IndexEntry) StatementPath(kind *StatementKind, id StatementID) (string, error) {
+	if e.Path == "" {
+		return kind.Path(id), nil
+	}
+
+	dir := path.Join(registryDir, kind.Dir) + "/"
+	if path.Clean(e.Path) != e.Path || !strings.HasPrefix(e.Path, dir) {
+		return "", fmt.Errorf("%w: statement '%s' has bad path in index: %s", ErrCorruptedRegistry, e.ID, e.Path)
+	}
+	return e.Path, nil
+}
+
+// Verify checks that the given encoded signed statement of the given kind matches the index entry.
+//
+// The statement signature is not verified, which is left to the provider loading the statement.
+func (e *IndexEntry) Verify(kind *StatementKind, data []byte) error {
+	if int64(len(data)) != e.Size {
+		return fmt.Errorf("%w: statement '%s' does not match index (size: %d expected: %d)",
+			ErrCorruptedRegistry, e.ID, len(data), e.Size,
+		)
+	}
+	if h := sha256.Sum256(data); hex.EncodeToString(h[:]) != e.SHA256 {
+		return fmt.Errorf("%w: statement '%s' does not match index (hash mismatch)", ErrCorruptedRegistry, e.ID)
+	}
+
+	var signed signature.Signed
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return fmt.Errorf("%w: failed to unmarshal signed statement '%s': %s", ErrCorruptedRegistry, e.ID, err)
+	}
+	stmt := kind.New()
+	if err := cbor.Unmarshal(signed.Blob, stmt); err != nil {
+		return fmt.Errorf("%w: failed to unmarshal statement '%s': %s", ErrCorruptedRegistry, e.ID, err)
+	}
+	if serial := stmt.StatementSerial(); serial != e.Serial {
+		return fmt.Errorf("%w: statement '%s' does not match index (serial: %d expected: %d)",
+			ErrCorruptedRegistry, e.ID, serial, e.Serial,
+		)
+	}
+	return nil
+}
+
+// NewIndex creates an index of all statements served by the given provider. In case the provider
+// implements SnapshotProvider, the index describes a single snapshot of the registry.
+func NewIndex(ctx context.Context, p StatementProvider) (*Index, error) {
+	return newIndex(ctx, p, nil)
+}
+
+// NewContentAddressedIndex creates an index of all statements served by the given provider like
+// NewIndex, but lists each statement under its content-addressed path (see ContentAddressedPath).
+// If not nil, fn is called with the path and the canonical encoding of each indexed statement, e.g.
+// to store or serve the statement under that path.
+func NewContentAddressedIndex(
+	ctx context.Context,
+	p StatementProvider,
+	fn func(path string, data []byte) error,
+) (*Index, error) {
+	return newIndex(ctx, p, func(kind *StatementKind, id StatementID, data []byte) (string, error) {
+		stmtPath := ContentAddressedPath(kind, id, data)
+		if fn != nil {
+			if err := fn(stmtPath, data); err != nil {
+				return "", err
+			}
+		}
+		return stmtPath, nil
+	})
+}
+
+// ContentAddressedPath returns the content-addressed path of the given encoded signed statement of
+// the given kind, relative to the registry base directory. As the path changes together with the
+// statement, clients can fetch all statements listed in an index under their content-addressed
+// paths even while the registry is being updated.
+func ContentAddressedPath(kind *StatementKind, id StatementID, data []byte) string {
+	h := sha256.Sum256(data)
+	return path.Join(registryDir, kind.Dir, kind.Filename(id), hex.EncodeToString(h[:])+statementExt)
+}
+
+// ParseContentAddressedPath returns the statement kind and the identifier of the statement stored
+// at the given content-addressed path (see ContentAddressedPath).
+func ParseContentAddressedPath(p string) (*StatementKind, StatementID, error) {
+	dir, name := path.Split(p)
+	hash := strings.TrimSuffix(name, statementExt)
+	if raw, err := hex.DecodeString(hash); err != nil || len(raw) != sha256.Size ||
+		hash != strings.ToLower(hash) || path.Clean(p) != p || path.Ext(name) != statementExt {
+		return nil, nil, fmt.Errorf("%w: %s", ErrBadFilename, p)
+	}
+	return ParseStatementPath(path.Clean(dir) + statementExt)
+}
+
+// newIndex creates an index of all statements served by the given provider. If not nil, fn is
+// called with the canonical encoding of each indexed statement and returns the path the statement
+// is stored at (see IndexEntry.Path).
+func newIndex(
+	ctx context.Context,
+	p StatementProvider,
+	fn func(kind *StatementKind, id StatementID, data []byte) (string, error),
+) (*Index, error) {
+	// Take the revision and all statements from a single snapshot of the registry (if supported).
+	p, err := Snapshot(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{
+		Revision:   p.Revision(),
+		Statements: make(map[string][]*IndexEntry),
+	}
+	for _, kind := range StatementKinds() {
+		// The serial number and the encoding of each statement come from the same load.
+		stmts, err := p.GetSignedStatements(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]*IndexEntry, 0, len(stmts))
+		for id, stmt := range stmts {
+			data, err := json.Marshal(stmt.Signed)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal %s metadata: %w", kind.Name, err)
+			}
+			var stmtPath string
+			if fn != nil {
+				if stmtPath, err = fn(kind, id, data); err != nil {
+					return nil, err
+				}
+			}
+
+			h := sha256.Sum256(data)
+			entries = append(entries, &IndexEntry{
+				ID:     kind.Filename(id),
+				Serial: stmt.Statement.StatementSerial(),
+				Size:   int64(len(data)),
+				SHA256: hex.EncodeToString(h[:]),
+				Path:   stmtPath,
+			})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].ID < entries[j].ID
+		})
+		index.Statements[kind.Name] = entries
+	}
+	index.Hash = index.ComputeHash()
+	return index, nil
+}
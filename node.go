@@ -0,0 +1,238 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+var (
+	// ErrNoSuchNode is the error returned where the requested node cannot be found.
+	ErrNoSuchNode = errors.New("registry: no such node")
+
+	// ErrNodeRemoved is the error returned where a node statement has been removed by an update.
+	ErrNodeRemoved = errors.New("registry: node removed")
+)
+
+const (
+	// MaxNodeRegionLength is the maximum length of the node metadata's Region field.
+	MaxNodeRegionLength = 64
+	// MaxNodeHostingProviderLength is the maximum length of the node metadata's HostingProvider
+	// field.
+	MaxNodeHostingProviderLength = 64
+	// MaxNodeSentryAddresses is the maximum number of the node metadata's SentryAddresses.
+	MaxNodeSentryAddresses = 16
+	// MaxNodeSentryAddressLength is the maximum length of a single node sentry address.
+	MaxNodeSentryAddressLength = 128
+	// MaxNodeContactLength is the maximum length of the node metadata's Contact field.
+	MaxNodeContactLength = 64
+
+	// MinSupportedNodeVersion is the minimum supported node metadata version.
+	MinSupportedNodeVersion = 1
+	// MaxSupportedNodeVersion is the maximum supported node metadata version.
+	MaxSupportedNodeVersion = 1
+)
+
+// NodeMetadataSignatureContext is the domain separation context used for node metadata.
+var NodeMetadataSignatureContext = signature.NewContext("oasis-metadata-registry: node")
+
+// NodeStatementKind is the node metadata statement kind.
+var NodeStatementKind = &StatementKind{
+	Name:               "node",
+	Dir:                registryNodeDir,
+	SignatureContext:   NodeMetadataSignatureContext,
+	MaxSize:            MaxStatementSize,
+	Optional:           true,
+	ErrNoSuchStatement: ErrNoSuchNode,
+	ErrRemoved:         ErrNodeRemoved,
+	New: func() Statement {
+		return new(NodeMetadata)
+	},
+	ParseFilename: parsePublicKeyFilename,
+	Filename:      publicKeyFilename,
+	ID: func(_ signature.PublicKey, stmt Statement) StatementID {
+		return stmt.(*NodeMetadata).Node
+	},
+	VerifySigner: func(signer signature.PublicKey, stmt Statement) error {
+		return stmt.(*NodeMetadata).verifySigner(signer)
+	},
+	VerifyCreate: func(_ context.Context, _ *VerifyOptions, stmt Statement, signer signature.PublicKey) error {
+		return stmt.(*NodeMetadata).verifyCreate(signer)
+	},
+	VerifyUpdate: func(src, dst Statement, signer signature.PublicKey) error {
+		return dst.(*NodeMetadata).verifyUpdate(src.(*NodeMetadata), signer)
+	},
+}
+
+var _ Statement = (*NodeMetadata)(nil)
+
+// NodeMetadata contains metadata about a node.
+type NodeMetadata struct {
+	cbor.Versioned
+
+	// Serial is the serial number of the node metadata statement.
+	Serial uint64 `json:"serial"`
+
+	// Node is the node's public key.
+	Node signature.PublicKey `json:"node"`
+
+	// Entity is the public key of the entity controlling the node. When set, updates of the
+	// statement may be signed by the entity instead of by the node. Only the node may create the
+	// statement and change its entity.
+	Entity *signature.PublicKey `json:"entity,omitempty"`
+
+	// Region is the region where the node is located.
+	Region string `json:"region,omitempty"`
+
+	// HostingProvider is the name of the node's hosting provider.
+	HostingProvider string `json:"hosting_provider,omitempty"`
+
+	// SentryAddresses are the node's public sentry addresses in the <pubkey>@<host>:<port> or
+	// <host>:<port> form.
+	SentryAddresses []string `json:"sentry_addresses,omitempty"`
+
+	// Contact is the node operator's contact information.
+	Contact string `json:"contact,omitempty"`
+}
+
+// Equal compares vs another node metadata for equality.
+func (n *NodeMetadata) Equal(other *NodeMetadata) bool {
+	return bytes.Equal(cbor.Marshal(n), cbor.Marshal(other))
+}
+
+// StatementSerial returns the serial number of the node metadata statement.
+func (n *NodeMetadata) StatementSerial() uint64 {
+	return n.Serial
+}
+
+// ValidateBasic performs basic validity checks on the node metadata.
+//
+// In case the metadata is invalid, the first validation error is returned. Use ValidateFields to
+// get all of them.
+func (n *NodeMetadata) ValidateBasic() error {
+	if errs := n.ValidateFields(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateFields performs basic validity checks on the node metadata and returns all field
+// validation errors.
+func (n *NodeMetadata) ValidateFields() ValidationErrors {
+	var v validator
+	v.version("v", "node metadata", n.Versioned.V, MinSupportedNodeVersion, MaxSupportedNodeVersion)
+	if n.Node == (signature.PublicKey{}) || !n.Node.IsValid() {
+		v.formatf("node", "node public key is missing or invalid")
+	}
+	if n.Entity != nil && !n.Entity.IsValid() {
+		v.formatf("entity", "node entity public key is invalid")
+	}
+	v.maxLength("region", "node region", n.Region, MaxNodeRegionLength)
+	v.maxLength("hosting_provider", "node hosting provider", n.HostingProvider, MaxNodeHostingProviderLength)
+	v.maxCount("sentry_addresses", "node sentry addresses", len(n.SentryAddresses), MaxNodeSentryAddresses)
+	for i, addr := range n.SentryAddresses {
+		v.sentryAddress(fmt.Sprintf("sentry_addresses[%d]", i), "node sentry address", addr, MaxNodeSentryAddressLength)
+	}
+	v.maxLength("contact", "node contact", n.Contact, MaxNodeContactLength)
+	return v.errs
+}
+
+// verifySigner checks that the given signer is either the node or its controlling entity.
+func (n *NodeMetadata) verifySigner(signer signature.PublicKey) error {
+	if signer.Equal(n.Node) || (n.Entity != nil && signer.Equal(*n.Entity)) {
+		return nil
+	}
+	return fmt.Errorf("node metadata signer is neither the node nor its entity (signer: %s)", signer)
+}
+
+// verifyCreate checks that new node metadata is signed by the node itself, so that no entity can
+// claim a node it does not control.
+func (n *NodeMetadata) verifyCreate(signer signature.PublicKey) error {
+	if signer.Equal(n.Node) {
+		return nil
+	}
+	return newStatementError(ErrSignerMismatch,
+		fmt.Errorf("new node metadata must be signed by the node (signer: %s)", signer),
+	)
+}
+
+// verifyUpdate checks that the node metadata signed by signer is a valid update of the existing
+// node metadata. Only the node itself may change its controlling entity.
+func (n *NodeMetadata) verifyUpdate(existing *NodeMetadata, signer signature.PublicKey) error {
+	if signer.Equal(n.Node) || nodeEntitiesEqual(n.Entity, existing.Entity) {
+		return nil
+	}
+	return newStatementError(ErrSignerMismatch,
+		fmt.Errorf("updated node metadata changing the node entity must be signed by the node (signer: %s)",
+			signer,
+		),
+	)
+}
+
+func nodeEntitiesEqual(a, b *signature.PublicKey) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Load loads and verifies node metadata from a given reader containing signed node metadata.
+func (n *NodeMetadata) Load(id signature.PublicKey, r io.Reader) error {
+	return NodeStatementKind.load(id, r, n)
+}
+
+// PrettyPrint writes a pretty-printed representation of NodeMetadata to the
+// given writer.
+func (n *NodeMetadata) PrettyPrint(ctx context.Context, prefix string, w io.Writer) {
+	entity := ""
+	if n.Entity != nil {
+		entity = n.Entity.String()
+	}
+	fmt.Fprintf(w, "%sVersion:          %d\n", prefix, n.V)
+	fmt.Fprintf(w, "%sSerial:           %d\n", prefix, n.Serial)
+	fmt.Fprintf(w, "%sNode:             %s\n", prefix, n.Node)
+	fmt.Fprintf(w, "%sEntity:           %s\n", prefix, entity)
+	fmt.Fprintf(w, "%sRegion:           %s\n", prefix, n.Region)
+	fmt.Fprintf(w, "%sHosting provider: %s\n", prefix, n.HostingProvider)
+	fmt.Fprintf(w, "%sSentry addresses: %s\n", prefix, strings.Join(n.SentryAddresses, ", "))
+	fmt.Fprintf(w, "%sContact:          %s\n", prefix, n.Contact)
+}
+
+// PrettyType returns a representation of NodeMetadata that can be used for
+// pretty printing.
+func (n NodeMetadata) PrettyType() (interface{}, error) {
+	return n, nil
+}
+
+// SignedNodeMetadata is a signed node metadata statement.
+type SignedNodeMetadata struct {
+	signature.Signed
+}
+
+// Open first verifies the blob signature and then unmarshals the blob.
+func (s *SignedNodeMetadata) Open(meta *NodeMetadata) error {
+	return s.Signed.Open(NodeMetadataSignatureContext, meta)
+}
+
+// Save serializes and writes node metadata to the given writer.
+func (s *SignedNodeMetadata) Save(w io.Writer) error {
+	return saveSigned(w, &s.Signed)
+}
+
+// SignNodeMetadata serializes the NodeMetadata and signs the result.
+func SignNodeMetadata(signer signature.Signer, meta *NodeMetadata) (*SignedNodeMetadata, error) {
+	signed, err := signature.SignSigned(signer, NodeMetadataSignatureContext, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedNodeMetadata{
+		Signed: *signed,
+	}, nil
+}
@@ -0,0 +1,297 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeMetadataValidateFields(t *testing.T) {
+	require := require.New(t)
+
+	nodeSigner := memorySigner.NewTestSigner("metadata-registry-tools test node signer")
+	node := &NodeMetadata{
+		Versioned:       cbor.NewVersioned(1),
+		Node:            nodeSigner.Public(),
+		Region:          "eu-central",
+		HostingProvider: "Hello Cloud",
+		SentryAddresses: []string{
+			"1.2.3.4:26656",
+			nodeSigner.Public().String() + "@sentry.helloworld.io:26656",
+		},
+		Contact: "hello@world.org",
+	}
+	require.Empty(node.ValidateFields(), "ValidateFields should not fail on valid metadata")
+
+	node.Versioned = cbor.NewVersioned(2)
+	node.SentryAddresses = []string{"1.2.3.4", "foo@1.2.3.4:26656", "1.2.3.4:0"}
+	errs := node.ValidateFields()
+	require.Len(errs, 4)
+	require.True(errors.Is(errs[0], ErrUnsupportedVersion))
+	for i, err := range errs[1:] {
+		require.Equal(RuleFormat, err.Rule)
+		require.Equal(fmt.Sprintf("sentry_addresses[%d]", i), err.Field)
+	}
+
+	node.Versioned = cbor.NewVersioned(1)
+	node.SentryAddresses = make([]string, MaxNodeSentryAddresses+1)
+	for i := range node.SentryAddresses {
+		node.SentryAddresses[i] = "1.2.3.4:26656"
+	}
+	errs = node.ValidateFields()
+	require.Len(errs, 1)
+	var tooLong *ErrFieldTooLong
+	require.True(errors.As(errs[0], &tooLong))
+	require.Equal("sentry_addresses", tooLong.Field)
+}
+
+func TestNodeMetadataLoad(t *testing.T) {
+	require := require.New(t)
+
+	nodeSigner := memorySigner.NewTestSigner("metadata-registry-tools test node signer")
+	entitySigner := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	otherSigner := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	load := func(id signature.PublicKey, signed *SignedNodeMetadata) error {
+		var buf bytes.Buffer
+		require.NoError(signed.Save(&buf), "Save")
+		return new(NodeMetadata).Load(id, &buf)
+	}
+
+	entityID := entitySigner.Public()
+	node := &NodeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Node:      nodeSigner.Public(),
+		Entity:    &entityID,
+		Region:    "eu-central",
+	}
+
+	// Signed by the node.
+	signed, err := SignNodeMetadata(nodeSigner, node)
+	require.NoError(err, "SignNodeMetadata")
+	require.NoError(load(nodeSigner.Public(), signed), "Load")
+
+	// Signed by the controlling entity.
+	signed, err = SignNodeMetadata(entitySigner, node)
+	require.NoError(err, "SignNodeMetadata")
+	require.NoError(load(nodeSigner.Public(), signed), "Load")
+
+	// Statement for a different node.
+	err = load(entitySigner.Public(), signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrSignerMismatch))
+
+	// Signed by neither the node nor the entity.
+	signed, err = SignNodeMetadata(otherSigner, node)
+	require.NoError(err, "SignNodeMetadata")
+	err = load(nodeSigner.Public(), signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrSignerMismatch))
+
+	// Entity signatures are not accepted for other signature contexts.
+	entitySigned, err := SignEntityMetadata(nodeSigner, &EntityMetadata{Versioned: cbor.NewVersioned(1)})
+	require.NoError(err, "SignEntityMetadata")
+	err = load(nodeSigner.Public(), &SignedNodeMetadata{Signed: entitySigned.Signed})
+	require.True(errors.Is(err, ErrBadSignature))
+}
+
+func TestFilesystemProviderNodes(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fs := memfs.New()
+	fp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	nodes, err := fp.GetNodes(ctx)
+	require.NoError(err, "GetNodes should work on an empty registry")
+	require.Empty(nodes)
+
+	nodeSigner := memorySigner.NewTestSigner("metadata-registry-tools test node signer")
+	entitySigner := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	otherSigner := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	entityID := entitySigner.Public()
+	node := &NodeMetadata{
+		Versioned:       cbor.NewVersioned(1),
+		Serial:          1,
+		Node:            nodeSigner.Public(),
+		Entity:          &entityID,
+		HostingProvider: "Hello Cloud",
+	}
+
+	signed, err := SignNodeMetadata(otherSigner, node)
+	require.NoError(err, "SignNodeMetadata")
+	err = fp.UpdateNode(signed)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateNode should fail for foreign signers")
+
+	signed, err = SignNodeMetadata(entitySigner, node)
+	require.NoError(err, "SignNodeMetadata")
+	err = fp.UpdateNode(signed)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateNode should fail for new nodes not signed by the node")
+
+	signed, err = SignNodeMetadata(nodeSigner, node)
+	require.NoError(err, "SignNodeMetadata")
+	require.NoError(fp.UpdateNode(signed), "UpdateNode")
+	err = fp.UpdateNode(signed)
+	require.True(errors.Is(err, ErrSerialNotIncreased), "UpdateNode should fail if serial number is not bumped")
+
+	fetchedNode, err := fp.GetNode(ctx, nodeSigner.Public())
+	require.NoError(err, "GetNode")
+	require.EqualValues(node, fetchedNode, "GetNode should return the same node")
+
+	_, err = fp.GetNode(ctx, entitySigner.Public())
+	require.Equal(ErrNoSuchNode, err)
+
+	nodes, err = fp.GetNodes(ctx)
+	require.NoError(err, "GetNodes")
+	require.Len(nodes, 1)
+	require.EqualValues(node, nodes[nodeSigner.Public()])
+	require.NoError(fp.Verify(), "Verify")
+
+	// Node statements cannot be removed by an update.
+	emptyFp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(emptyFp.Init(), "Init")
+	require.NoError(fp.VerifyUpdate(emptyFp), "VerifyUpdate")
+	err = emptyFp.VerifyUpdate(fp)
+	require.True(errors.Is(err, ErrNodeRemoved))
+
+	report, err := emptyFp.VerifyWithReport(context.Background(), fp)
+	require.NoError(err, "VerifyWithReport")
+	require.True(report.Failed())
+	require.Len(report.Entries, 1)
+	require.Equal(VerifyStatusRemoved, report.Entries[0].Status)
+
+	// Registries without a node directory have no nodes.
+	legacyFs := memfs.New()
+	require.NoError(legacyFs.MkdirAll(legacyFs.Join(registryDir, registryEntityDir), 0o755), "MkdirAll")
+	legacyFp, err := NewFilesystemProviderWithConfig(legacyFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(legacyFp.Verify(), "Verify")
+}
+
+func TestFilesystemProviderNodeEntityUpdate(t *testing.T) {
+	require := require.New(t)
+
+	nodeSigner := memorySigner.NewTestSigner("metadata-registry-tools test node signer")
+	entitySigner := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	otherSigner := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	entityID, otherID := entitySigner.Public(), otherSigner.Public()
+	// Statements are written directly, so that registries with invalid updates can be created.
+	newProvider := func(signer signature.Signer, node *NodeMetadata) MutableStatementProvider {
+		fs := memfs.New()
+		fp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{})
+		require.NoError(err, "NewFilesystemProviderWithConfig")
+		require.NoError(fp.Init(), "Init")
+		signed, err := SignNodeMetadata(signer, node)
+		require.NoError(err, "SignNodeMetadata")
+		f, err := fs.Create(NodeStatementKind.Path(node.Node))
+		require.NoError(err, "Create")
+		defer f.Close()
+		require.NoError(signed.Save(f), "Save")
+		return fp
+	}
+	node := &NodeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Node:      nodeSigner.Public(),
+		Entity:    &entityID,
+	}
+	fp := newProvider(nodeSigner, node)
+
+	// Foreign entities cannot take over the node by declaring themselves as its entity.
+	hijacked := *node
+	hijacked.Serial = 2
+	hijacked.Entity = &otherID
+	signed, err := SignNodeMetadata(otherSigner, &hijacked)
+	require.NoError(err, "SignNodeMetadata")
+	err = fp.UpdateNode(signed)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateNode should fail for foreign entities")
+
+	hijackedFp := newProvider(otherSigner, &hijacked)
+	err = hijackedFp.VerifyUpdate(fp)
+	require.True(errors.Is(err, ErrSignerMismatch), "VerifyUpdate should fail for foreign entities")
+	report, err := hijackedFp.VerifyWithReport(context.Background(), fp)
+	require.NoError(err, "VerifyWithReport")
+	require.True(report.Failed(), "VerifyWithReport should fail for foreign entities")
+
+	// The entity can update the node as long as it does not change the entity.
+	updated := *node
+	updated.Serial = 2
+	updated.Region = "eu-central"
+	signed, err = SignNodeMetadata(entitySigner, &updated)
+	require.NoError(err, "SignNodeMetadata")
+	require.NoError(fp.UpdateNode(signed), "UpdateNode")
+
+	// The node itself can change its entity.
+	hijacked.Serial = 3
+	signed, err = SignNodeMetadata(nodeSigner, &hijacked)
+	require.NoError(err, "SignNodeMetadata")
+	require.NoError(fp.UpdateNode(signed), "UpdateNode")
+	require.NoError(fp.VerifyUpdate(newProvider(entitySigner, node)), "VerifyUpdate")
+}
+
+func TestFilesystemProviderNodeSquatting(t *testing.T) {
+	require := require.New(t)
+
+	nodeSigner := memorySigner.NewTestSigner("metadata-registry-tools test node signer")
+	attackerSigner := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	attackerID := attackerSigner.Public()
+	squatted := &NodeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    math.MaxUint64,
+		Node:      nodeSigner.Public(),
+		Entity:    &attackerID,
+	}
+	signed, err := SignNodeMetadata(attackerSigner, squatted)
+	require.NoError(err, "SignNodeMetadata")
+
+	// A third party cannot create a statement for someone else's node by declaring itself as the
+	// node's entity.
+	emptyFp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(emptyFp.Init(), "Init")
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	err = fp.UpdateNode(signed)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateNode should fail for third-party signers")
+	_, err = fp.GetNode(context.Background(), nodeSigner.Public())
+	require.Equal(ErrNoSuchNode, err, "UpdateNode should not store the statement")
+
+	// Registry updates adding such a statement must be rejected as well.
+	fs := memfs.New()
+	squattedFp, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(squattedFp.Init(), "Init")
+	f, err := fs.Create(NodeStatementKind.Path(nodeSigner.Public()))
+	require.NoError(err, "Create")
+	require.NoError(signed.Save(f), "Save")
+	require.NoError(f.Close(), "Close")
+
+	err = squattedFp.VerifyUpdate(emptyFp)
+	require.True(errors.Is(err, ErrSignerMismatch), "VerifyUpdate should fail for third-party signers")
+	report, err := squattedFp.VerifyWithReport(context.Background(), emptyFp)
+	require.NoError(err, "VerifyWithReport")
+	require.True(report.Failed(), "VerifyWithReport should fail for third-party signers")
+	require.Equal(VerifyStatusSignerMismatch, report.Entries[0].Status)
+
+	// The node itself can still publish its metadata.
+	nodeSigned, err := SignNodeMetadata(nodeSigner, &NodeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Node:      nodeSigner.Public(),
+	})
+	require.NoError(err, "SignNodeMetadata")
+	require.NoError(fp.UpdateNode(nodeSigned), "UpdateNode")
+}
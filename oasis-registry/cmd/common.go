@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	signerFile "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/file"
+	signerPlugin "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/plugin"
+	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
+	cmdSigner "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/signer"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+const (
+	// cfgGitURL configures the URL of the Git repository containing the registry.
+	cfgGitURL = "git-url"
+	// cfgGitBranch configures the Git branch containing the registry.
+	cfgGitBranch = "git-branch"
+
+	// cfgFormat configures the output format.
+	cfgFormat = "format"
+
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+
+	// cfgSkipValidation configures whether the validation of the provided
+	// metadata should be skipped or not.
+	cfgSkipValidation = "skip-validation"
+
+	// cfgSignerRole configures the role of the key signing the metadata.
+	cfgSignerRole = "signer-role"
+
+	// cfgRegistryPath configures the path of a local registry to query instead of the Git registry.
+	cfgRegistryPath = "path"
+
+	// cfgOutput configures the output file.
+	cfgOutput = "output"
+
+	// cfgRuntimeOwner configures the entities owning runtimes, used to verify new runtime metadata.
+	cfgRuntimeOwner = "runtime-owner"
+)
+
+var (
+	// gitFlags are the flags used by subcommands operating on a Git-backed registry.
+	gitFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// formatFlags are the flags used by subcommands supporting multiple output formats.
+	formatFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// signFlags are the flags used by subcommands signing metadata statements.
+	signFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// signerRoleFlags are the flags used by subcommands signing metadata statements with keys of
+	// different roles.
+	signerRoleFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// queryFlags are the flags used by subcommands querying a local or a Git-backed registry.
+	queryFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// outputFlags are the flags used by subcommands writing their results to a file.
+	outputFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// runtimeOwnerFlags are the flags used by subcommands verifying new runtime metadata.
+	runtimeOwnerFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+// signerRoleFromFlags returns the configured role of the signing key or def if not configured.
+func signerRoleFromFlags(def signature.SignerRole) (signature.SignerRole, error) {
+	raw := viper.GetString(cfgSignerRole)
+	if raw == "" {
+		return def, nil
+	}
+
+	var role signature.SignerRole
+	if err := role.UnmarshalText([]byte(raw)); err != nil ||
+		(role != signature.SignerNode && role != signature.SignerEntity) {
+		return role, fmt.Errorf("invalid signer role: %s", raw)
+	}
+	return role, nil
+}
+
+// runtimeOwnersFromFlags returns the runtime owner lookup serving the runtime owners given by the
+// flags or nil if none are given.
+func runtimeOwnersFromFlags() (registry.RuntimeOwnerLookup, error) {
+	raw := viper.GetStringSlice(cfgRuntimeOwner)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	owners := make(registry.StaticRuntimeOwners)
+	for _, v := range raw {
+		rawID, rawOwner, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed runtime owner: %s", v)
+		}
+		var id common.Namespace
+		if err := id.UnmarshalHex(rawID); err != nil {
+			return nil, fmt.Errorf("malformed runtime ID '%s': %w", rawID, err)
+		}
+		owner, err := registry.ParsePublicKey(rawOwner)
+		if err != nil {
+			return nil, fmt.Errorf("malformed runtime owner '%s': %w", rawOwner, err)
+		}
+		owners[id] = owner
+	}
+	return owners, nil
+}
+
+func loadSigner(role signature.SignerRole) (signature.Signer, error) {
+	signerDir, err := cmdSigner.CLIDirOrPwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve signer dir: %w", err)
+	}
+	signerFactory, err := cmdSigner.NewFactory(cmdSigner.Backend(), signerDir, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer factory: %w", err)
+	}
+	signer, err := signerFactory.Load(role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signer: %w", err)
+	}
+	return signer, nil
+}
+
+// confirmSigning asks the user to confirm signing the previously shown statement and exits
+// unless confirmed. Notes for signer plugins are shown on the given writer, which is the same
+// writer the statement was shown on.
+func confirmSigning(w io.Writer) {
+	switch cmdSigner.Backend() {
+	case signerFile.SignerName:
+		if !cmdFlags.AssumeYes() {
+			if !cmdCommon.GetUserConfirmation("\nAre you sure you want to continue? (y)es/(n)o: ") {
+				os.Exit(1)
+			}
+		}
+	case signerPlugin.SignerName:
+		if cmdCommon.Isatty(os.Stdin.Fd()) {
+			fmt.Fprintln(w,
+				"\nYou may need to review the transaction on your device if you use a hardware-based signer plugin...",
+			)
+		}
+	}
+}
+
+// writeOutput writes the given data to the configured output file or to standard output if none
+// is configured.
+func writeOutput(data []byte) error {
+	filename := viper.GetString(cfgOutput)
+	if filename == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644) //nolint:gosec
+}
+
+// newQueryProvider returns the local registry provider configured by the flags or the Git registry
+// provider with the given configuration, together with a function that stops it.
+func newQueryProvider(cfg registry.GitConfig) (registry.StatementProvider, func()) {
+	if path := viper.GetString(cfgRegistryPath); path != "" {
+		p, err := registry.NewFilesystemPathProvider(path)
+		if err != nil {
+			registryLogger.Error("failed to create filesystem provider",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		return p.(registry.StatementProvider), func() {}
+	}
+
+	gp := newGitProvider(cfg)
+	return gp, gp.Stop
+}
+
+// newGitProvider returns the Git registry provider with the given configuration.
+func newGitProvider(cfg registry.GitConfig) registry.GitProvider {
+	p, err := registry.NewGitProvider(cfg)
+	if err != nil {
+		registryLogger.Error("failed to create Git registry provider",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	return p.(registry.GitProvider)
+}
+
+func gitConfigFromFlags() registry.GitConfig {
+	cfg := registry.NewGitConfig()
+	cfg.URL = viper.GetString(cfgGitURL)
+	cfg.Branch = viper.GetString(cfgGitBranch)
+	return cfg
+}
+
+func init() { //nolint:gochecknoinits
+	defaultGitCfg := registry.NewGitConfig()
+	gitFlags.String(cfgGitURL, defaultGitCfg.URL, "registry Git repository URL")
+	gitFlags.String(cfgGitBranch, defaultGitCfg.Branch, "registry Git branch")
+	_ = viper.BindPFlags(gitFlags)
+
+	formatFlags.String(cfgFormat, formatText, "output format [text,json,csv] (not supported by all subcommands)")
+	_ = viper.BindPFlags(formatFlags)
+
+	signFlags.Bool(cfgSkipValidation, false, "skip metadata validation")
+	signFlags.AddFlagSet(cmdSigner.Flags)
+	signFlags.AddFlagSet(cmdSigner.CLIFlags)
+	signFlags.AddFlagSet(cmdFlags.AssumeYesFlag)
+	_ = viper.BindPFlags(signFlags)
+
+	signerRoleFlags.String(cfgSignerRole, "", "role of the signing key [node,entity] (default depends on statement kind)")
+	_ = viper.BindPFlags(signerRoleFlags)
+
+	queryFlags.String(cfgRegistryPath, "", "path to a local registry (default: use the Git registry)")
+	_ = viper.BindPFlags(queryFlags)
+
+	outputFlags.StringP(cfgOutput, "o", "", "output file (default: standard output)")
+	_ = viper.BindPFlags(outputFlags)
+
+	runtimeOwnerFlags.StringSlice(cfgRuntimeOwner, nil, "entity owning a runtime (<runtime-id>=<entity-public-key>)")
+	_ = viper.BindPFlags(runtimeOwnerFlags)
+}
@@ -1,29 +1,40 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
-	signerFile "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/file"
-	signerPlugin "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/plugin"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
-	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
-	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
-	cmdSigner "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/signer"
 
 	registry "github.com/oasisprotocol/metadata-registry-tools"
 )
 
-// cfgSkipValidation configures whether the validation of the provided entity
-// metadata should be skipped or not.
-const cfgSkipValidation = "skip-validation"
+const (
+	// cfgRevocationReason configures the reason for revoking entity metadata.
+	cfgRevocationReason = "reason"
+
+	// cfgPublicKey configures the public key of an offline signer.
+	cfgPublicKey = "public-key"
+
+	// cfgAutoSerial configures the mode of automatically assigning the serial number.
+	cfgAutoSerial = "auto-serial"
+)
 
 var (
 	entityCmd = &cobra.Command{
@@ -37,7 +48,67 @@ var (
 		Run:   doEntityUpdate,
 	}
 
-	entityFlags = flag.NewFlagSet("", flag.ContinueOnError)
+	entityUpdateFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	entitySignCmd = &cobra.Command{
+		Use:   "sign <metadata.json>",
+		Short: "sign entity metadata without updating a registry",
+		Args:  cobra.ExactArgs(1),
+		Run:   doEntitySign,
+	}
+
+	entityImportCmd = &cobra.Command{
+		Use:   "import <signed-metadata.json>",
+		Short: "import signed entity metadata into the registry",
+		Args:  cobra.ExactArgs(1),
+		Run:   doEntityImport,
+	}
+
+	entityRevokeCmd = &cobra.Command{
+		Use:   "revoke",
+		Short: "revoke an entity's metadata in the registry",
+		Args:  cobra.NoArgs,
+		Run:   doEntityRevoke,
+	}
+
+	entityRevokeFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	entityPrepareCmd = &cobra.Command{
+		Use:   "prepare <metadata.json>",
+		Short: "prepare entity metadata for signing by an offline signer",
+		Args:  cobra.ExactArgs(1),
+		Run:   doEntityPrepare,
+	}
+
+	entityAttachSignatureCmd = &cobra.Command{
+		Use:   "attach-signature <metadata.json> <signature-file>",
+		Short: "attach an offline signature to entity metadata and update the entity in the registry",
+		Args:  cobra.ExactArgs(2),
+		Run:   doEntityAttachSignature,
+	}
+
+	entityAttachSignatureFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	entityShowCmd = &cobra.Command{
+		Use:   "show <public-key|signed-metadata.json>",
+		Short: "show an entity's metadata",
+		Args:  cobra.ExactArgs(1),
+		Run:   doEntityShow,
+	}
+
+	entityListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list metadata of all entities in the registry",
+		Args:  cobra.NoArgs,
+		Run:   doEntityList,
+	}
+
+	entityHistoryCmd = &cobra.Command{
+		Use:   "history <public-key>",
+		Short: "show the history of an entity's metadata in a Git registry",
+		Args:  cobra.ExactArgs(1),
+		Run:   doEntityHistory,
+	}
 
 	entityLogger = logging.GetLogger("cmd/entity")
 )
@@ -47,20 +118,217 @@ func logErrorAndExit(msg string, err error) {
 	os.Exit(1)
 }
 
-func loadSigner() (signature.Signer, error) {
-	signerDir, err := cmdSigner.CLIDirOrPwd()
+// parseRawSignature parses a hex or Base64-encoded raw signature.
+func parseRawSignature(raw string) (signature.RawSignature, error) {
+	var sig signature.RawSignature
+	if b, err := hex.DecodeString(raw); err == nil {
+		if err = sig.UnmarshalBinary(b); err != nil {
+			return sig, fmt.Errorf("malformed signature: %w", err)
+		}
+		return sig, nil
+	}
+	if err := sig.UnmarshalText([]byte(raw)); err != nil {
+		return sig, fmt.Errorf("malformed signature '%s'", raw)
+	}
+	return sig, nil
+}
+
+// entityField is a named entity metadata field used for comparing metadata versions.
+type entityField struct {
+	name  string
+	value string
+}
+
+func entityMetadataFields(e *registry.EntityMetadata) []entityField {
+	return []entityField{
+		{"Version", strconv.FormatUint(uint64(e.V), 10)},
+		{"Name", e.Name},
+		{"URL", e.URL},
+		{"Email", e.Email},
+		{"Keybase", e.Keybase},
+		{"Twitter", e.Twitter},
+		{"Description", e.Description},
+		{"Logo", e.Logo},
+		{"Country", e.Country},
+		{"Discord", e.Discord},
+		{"Telegram", e.Telegram},
+		{"GitHub", e.GitHub},
+		{"CommissionPolicy", e.CommissionPolicy},
+		{"Revoked", strconv.FormatBool(e.Revoked)},
+		{"RevocationReason", e.RevocationReason},
+	}
+}
+
+// entityRecord is entity metadata together with the entity's public key.
+type entityRecord struct {
+	Entity   signature.PublicKey      `json:"entity"`
+	Metadata *registry.EntityMetadata `json:"metadata"`
+}
+
+// entityFormatFromFlags returns the configured output format and exits in case it is not supported.
+func entityFormatFromFlags() string {
+	switch format := viper.GetString(cfgFormat); format {
+	case formatText, formatJSON, formatCSV:
+		return format
+	default:
+		entityLogger.Error("unsupported output format",
+			"format", format,
+		)
+		os.Exit(1)
+		return ""
+	}
+}
+
+// writeEntities writes the given entity records in the given format. Unless list is set, a single
+// record is expected.
+func writeEntities(format string, records []entityRecord, list bool) {
+	switch format {
+	case formatText:
+		for i, record := range records {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("Entity: %s\n", record.Entity)
+			record.Metadata.PrettyPrint(context.Background(), "  ", os.Stdout)
+		}
+	case formatJSON:
+		var v interface{} = records
+		if !list {
+			v = records[0]
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			logErrorAndExit("failed to marshal entity metadata", err)
+		}
+		fmt.Println(string(data))
+	case formatCSV:
+		w := csv.NewWriter(os.Stdout)
+		header := []string{"Entity"}
+		for _, field := range entityMetadataFields(&registry.EntityMetadata{}) {
+			header = append(header, field.name)
+		}
+		_ = w.Write(header)
+		for _, record := range records {
+			row := []string{record.Entity.String()}
+			for _, field := range entityMetadataFields(record.Metadata) {
+				row = append(row, field.value)
+			}
+			_ = w.Write(row)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			logErrorAndExit("failed to write entity metadata", err)
+		}
+	}
+}
+
+func doEntityShow(cmd *cobra.Command, args []string) {
+	format := entityFormatFromFlags()
+
+	// Signed entity metadata statements can be shown directly, after being verified in the same
+	// way as statements loaded from the registry.
+	if fi, err := os.Stat(args[0]); err == nil && !fi.IsDir() {
+		raw, err := os.ReadFile(args[0])
+		if err != nil {
+			logErrorAndExit("failed to read signed entity descriptor", err)
+		}
+
+		var signed registry.SignedEntityMetadata
+		if err = json.Unmarshal(raw, &signed); err != nil {
+			logErrorAndExit("failed to parse signed entity metadata", err)
+		}
+		id := signed.Signature.PublicKey
+		meta, err := registry.EntityStatementKind.Load(id, bytes.NewReader(raw))
+		if err != nil {
+			logErrorAndExit("failed to verify signed entity metadata", err)
+		}
+
+		writeEntities(format, []entityRecord{{id, meta.(*registry.EntityMetadata)}}, false)
+		return
+	}
+
+	id, err := registry.ParsePublicKey(args[0])
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve signer dir: %w", err)
+		logErrorAndExit("failed to parse entity public key", err)
+	}
+
+	p, stop := newQueryProvider(gitConfigFromFlags())
+	defer stop()
+
+	// Revocation tombstones are shown as well.
+	meta, err := p.GetEntity(context.Background(), id)
+	if err != nil && !errors.Is(err, registry.ErrEntityRevoked) {
+		logErrorAndExit("failed to get entity metadata", err)
 	}
-	signerFactory, err := cmdSigner.NewFactory(cmdSigner.Backend(), signerDir, signature.SignerEntity)
+
+	writeEntities(format, []entityRecord{{id, meta}}, false)
+}
+
+func doEntityList(cmd *cobra.Command, args []string) {
+	format := entityFormatFromFlags()
+
+	p, stop := newQueryProvider(gitConfigFromFlags())
+	defer stop()
+
+	entities, err := p.GetEntities(context.Background())
+	if err != nil {
+		logErrorAndExit("failed to get entities", err)
+	}
+
+	records := make([]entityRecord, 0, len(entities))
+	for id, meta := range entities {
+		records = append(records, entityRecord{id, meta})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return bytes.Compare(records[i].Entity[:], records[j].Entity[:]) < 0
+	})
+
+	writeEntities(format, records, true)
+}
+
+func doEntityHistory(cmd *cobra.Command, args []string) {
+	id, err := registry.ParsePublicKey(args[0])
 	if err != nil {
-		return nil, fmt.Errorf("failed to create signer factory: %w", err)
+		logErrorAndExit("failed to parse entity public key", err)
+	}
+
+	var history []*registry.EntityHistoryRecord
+	if path := viper.GetString(cfgRegistryPath); path != "" {
+		// Local checkouts are inspected without accessing the remote.
+		history, err = registry.GetGitEntityHistory(context.Background(), path, "HEAD", id)
+	} else {
+		cfg := gitConfigFromFlags()
+		cfg.FullHistory = true
+		gp := newGitProvider(cfg)
+		defer gp.Stop()
+
+		history, err = gp.GetEntityHistory(context.Background(), id)
 	}
-	signer, err := signerFactory.Load(signature.SignerEntity)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load signer: %w", err)
+		logErrorAndExit("failed to get entity history", err)
+	}
+	if len(history) == 0 {
+		logErrorAndExit("failed to get entity history", registry.ErrNoSuchEntity)
+	}
+
+	fmt.Printf("History of entity %s:\n", id)
+	for i, record := range history {
+		fmt.Printf("\nCommit: %s\n", record.Commit)
+		fmt.Printf("Date:   %s\n", record.Time.UTC().Format(time.RFC3339))
+		fmt.Printf("Serial: %d\n", record.Serial)
+
+		if i == 0 {
+			record.Metadata.PrettyPrint(context.Background(), "  ", os.Stdout)
+			continue
+		}
+
+		prevFields := entityMetadataFields(history[i-1].Metadata)
+		for j, field := range entityMetadataFields(record.Metadata) {
+			if prev := prevFields[j]; prev.value != field.value {
+				fmt.Printf("  %s: %q -> %q\n", field.name, prev.value, field.value)
+			}
+		}
 	}
-	return signer, nil
 }
 
 func doEntityUpdate(cmd *cobra.Command, args []string) {
@@ -69,50 +337,150 @@ func doEntityUpdate(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	var prepare statementPreparer
+	if mode := registry.SerialMode(viper.GetString(cfgAutoSerial)); mode != "" {
+		p := newFsProvider()
+		prepare = func(signer signature.PublicKey, stmt registry.Statement) {
+			err := registry.AssignEntitySerial(context.Background(), p, signer, stmt.(*registry.EntityMetadata), mode)
+			if err != nil {
+				logErrorAndExit("failed to assign serial number", err)
+			}
+		}
+	}
+
+	updateStatement(entityLogger, registry.EntityStatementKind, signature.SignerEntity, args[0], prepare)
+}
+
+func doEntitySign(cmd *cobra.Command, args []string) {
+	// Show the descriptor on standard error as the signed statement may be written to standard
+	// output.
+	_, signed := signStatement(
+		entityLogger, registry.EntityStatementKind, signature.SignerEntity, args[0], nil, os.Stderr,
+	)
+
+	// Use the same serialization as the registry so the output can be stored there as is.
+	data, err := json.Marshal(signed)
+	if err != nil {
+		logErrorAndExit("failed to marshal metadata", err)
+	}
+	if err = writeOutput(data); err != nil {
+		logErrorAndExit("failed to write metadata", err)
+	}
+}
+
+func doEntityImport(cmd *cobra.Command, args []string) {
 	p := newFsProvider()
 
-	// Open and parse the passed entity metadata file.
-	rawEntity, err := os.ReadFile(args[0])
+	raw, err := os.ReadFile(args[0])
 	if err != nil {
-		logErrorAndExit("failed to read entity descriptor", err)
+		logErrorAndExit("failed to read signed entity descriptor", err)
 	}
 
-	var entity registry.EntityMetadata
-	if err = json.Unmarshal(rawEntity, &entity); err != nil {
-		logErrorAndExit("failed to parse serialized entity metadata", err)
+	var signed registry.SignedEntityMetadata
+	if err = json.Unmarshal(raw, &signed); err != nil {
+		logErrorAndExit("failed to parse signed entity metadata", err)
 	}
 
-	if !viper.GetBool(cfgSkipValidation) {
-		if err = entity.ValidateBasic(); err != nil {
-			logErrorAndExit("provided entity metadata is invalid", err)
-		}
+	var meta registry.EntityMetadata
+	if err = signed.Open(&meta); err != nil {
+		logErrorAndExit("failed to verify signed entity metadata", err)
+	}
+	validateStatement(entityLogger, registry.EntityStatementKind, &meta)
+
+	if err = p.UpdateEntity(&signed); err != nil {
+		logErrorAndExit("failed to update metadata", err)
 	}
 
+	fmt.Printf("Updated entity %s\n", signed.Signature.PublicKey)
+}
+
+func doEntityPrepare(cmd *cobra.Command, args []string) {
+	meta := readStatement(entityLogger, registry.EntityStatementKind, args[0]).(*registry.EntityMetadata)
+	validateStatement(entityLogger, registry.EntityStatementKind, meta)
+
+	req, err := registry.PrepareEntityMetadata(meta)
+	if err != nil {
+		logErrorAndExit("failed to prepare metadata", err)
+	}
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		logErrorAndExit("failed to marshal signing request", err)
+	}
+
+	// Show the descriptor on standard error as the signing request may be written to standard
+	// output.
+	fmt.Fprintf(os.Stderr, "Prepared the following entity metadata descriptor for signing:\n")
+	meta.PrettyPrint(context.Background(), "  ", os.Stderr)
+	fmt.Fprintf(os.Stderr, "\nSignature context: %s\n", req.SignatureContext)
+	fmt.Fprintf(os.Stderr, "Digest:            %s\n", req.Digest)
+
+	if err = writeOutput(append(data, '\n')); err != nil {
+		logErrorAndExit("failed to write signing request", err)
+	}
+}
+
+func doEntityAttachSignature(cmd *cobra.Command, args []string) {
+	p := newFsProvider()
+
+	meta := readStatement(entityLogger, registry.EntityStatementKind, args[0]).(*registry.EntityMetadata)
+	signer, err := registry.ParsePublicKey(viper.GetString(cfgPublicKey))
+	if err != nil {
+		logErrorAndExit("failed to parse signer public key", err)
+	}
+	raw, err := os.ReadFile(args[1])
+	if err != nil {
+		logErrorAndExit("failed to read signature", err)
+	}
+	sig, err := parseRawSignature(strings.TrimSpace(string(raw)))
+	if err != nil {
+		logErrorAndExit("failed to parse signature", err)
+	}
+
+	signed, err := registry.AttachEntityMetadataSignature(meta, signer, sig)
+	if err != nil {
+		logErrorAndExit("failed to attach signature", err)
+	}
+
+	if err = p.UpdateEntity(signed); err != nil {
+		logErrorAndExit("failed to update metadata", err)
+	}
+
+	fmt.Printf("Updated entity %s\n", signer)
+}
+
+func doEntityRevoke(cmd *cobra.Command, args []string) {
+	p := newFsProvider()
+
 	// Get the signer.
-	signer, err := loadSigner()
+	signer, err := loadSigner(signature.SignerEntity)
 	if err != nil {
 		logErrorAndExit("failed to load signer", err)
 	}
 
-	// Show descriptor and ask for confirmation.
-	fmt.Printf("You are about to sign the following entity metadata descriptor:\n")
-	entity.PrettyPrint(context.Background(), "  ", os.Stdout)
-
-	switch cmdSigner.Backend() {
-	case signerFile.SignerName:
-		if !cmdFlags.AssumeYes() {
-			if !cmdCommon.GetUserConfirmation("\nAre you sure you want to continue? (y)es/(n)o: ") {
-				os.Exit(1)
-			}
-		}
-	case signerPlugin.SignerName:
-		if cmdCommon.Isatty(os.Stdin.Fd()) {
-			fmt.Println("\nYou may need to review the transaction on your device if you use a hardware-based signer plugin...")
-		}
+	// The revocation tombstone must supersede the existing entity metadata.
+	existing, err := p.GetEntity(context.Background(), signer.Public())
+	if err != nil {
+		logErrorAndExit("failed to get existing entity metadata", err)
+	}
+
+	revocation := registry.EntityMetadata{
+		Versioned:        cbor.NewVersioned(registry.MaxSupportedVersion),
+		Serial:           existing.Serial + 1,
+		Revoked:          true,
+		RevocationReason: viper.GetString(cfgRevocationReason),
+	}
+	if err = revocation.ValidateBasic(); err != nil {
+		logErrorAndExit("provided entity revocation is invalid", err)
 	}
 
+	// Show descriptor and ask for confirmation.
+	fmt.Printf("You are about to revoke the metadata of entity %s by signing:\n", signer.Public())
+	revocation.PrettyPrint(context.Background(), "  ", os.Stdout)
+	fmt.Printf("\nRevoked entity metadata cannot be updated anymore.\n")
+	confirmSigning(os.Stdout)
+
 	// Sign the descriptor.
-	signed, err := registry.SignEntityMetadata(signer, &entity)
+	signed, err := registry.SignEntityMetadata(signer, &revocation)
 	if err != nil {
 		logErrorAndExit("failed to sign metadata", err)
 	}
@@ -121,18 +489,43 @@ func doEntityUpdate(cmd *cobra.Command, args []string) {
 		logErrorAndExit("failed to update metadata", err)
 	}
 
-	fmt.Printf("Updated entity %s\n", signer.Public())
+	fmt.Printf("Revoked entity %s\n", signer.Public())
 }
 
 func init() { //nolint:gochecknoinits
-	entityFlags.Bool(cfgSkipValidation, false, "skip metadata validation")
-	entityFlags.AddFlagSet(cmdSigner.Flags)
-	entityFlags.AddFlagSet(cmdSigner.CLIFlags)
-	entityFlags.AddFlagSet(cmdFlags.AssumeYesFlag)
-	_ = viper.BindPFlags(entityFlags)
+	entityUpdateFlags.String(cfgAutoSerial, "", "automatically assign the serial number [increment,timestamp]")
+	entityUpdateFlags.Lookup(cfgAutoSerial).NoOptDefVal = string(registry.SerialModeIncrement)
+	_ = viper.BindPFlags(entityUpdateFlags)
+	entityUpdateCmd.Flags().AddFlagSet(entityUpdateFlags)
+	entityUpdateCmd.Flags().AddFlagSet(signFlags)
 
-	entityUpdateCmd.Flags().AddFlagSet(entityFlags)
+	entityRevokeFlags.String(cfgRevocationReason, "", "reason for revoking the entity metadata")
+	_ = viper.BindPFlags(entityRevokeFlags)
+	entityRevokeCmd.Flags().AddFlagSet(entityRevokeFlags)
+	entityRevokeCmd.Flags().AddFlagSet(signFlags)
+	entitySignCmd.Flags().AddFlagSet(signFlags)
+	entitySignCmd.Flags().AddFlagSet(outputFlags)
+	entityPrepareCmd.Flags().AddFlagSet(outputFlags)
+
+	entityAttachSignatureFlags.String(cfgPublicKey, "", "public key of the entity signing the metadata")
+	_ = viper.BindPFlags(entityAttachSignatureFlags)
+	entityAttachSignatureCmd.Flags().AddFlagSet(entityAttachSignatureFlags)
+	for _, cmd := range []*cobra.Command{entityShowCmd, entityListCmd} {
+		cmd.Flags().AddFlagSet(queryFlags)
+		cmd.Flags().AddFlagSet(gitFlags)
+		cmd.Flags().AddFlagSet(formatFlags)
+	}
+	entityHistoryCmd.Flags().AddFlagSet(queryFlags)
+	entityHistoryCmd.Flags().AddFlagSet(gitFlags)
 
 	// Register all of the sub-commands.
 	entityCmd.AddCommand(entityUpdateCmd)
+	entityCmd.AddCommand(entitySignCmd)
+	entityCmd.AddCommand(entityImportCmd)
+	entityCmd.AddCommand(entityRevokeCmd)
+	entityCmd.AddCommand(entityPrepareCmd)
+	entityCmd.AddCommand(entityAttachSignatureCmd)
+	entityCmd.AddCommand(entityShowCmd)
+	entityCmd.AddCommand(entityListCmd)
+	entityCmd.AddCommand(entityHistoryCmd)
 }
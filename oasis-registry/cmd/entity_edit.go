@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+// clearFieldValue is the answer clearing an entity metadata field when editing it interactively.
+const clearFieldValue = "-"
+
+var entityEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "interactively edit (or create) an entity's metadata in the registry",
+	Args:  cobra.NoArgs,
+	Run:   doEntityEdit,
+}
+
+// editableEntityField is an entity metadata field that can be edited interactively.
+type editableEntityField struct {
+	name     string
+	field    string
+	value    *string
+	extended bool
+}
+
+func editableEntityFields(e *registry.EntityMetadata) []editableEntityField {
+	return []editableEntityField{
+		{"Name", "name", &e.Name, false},
+		{"URL", "url", &e.URL, false},
+		{"Email", "email", &e.Email, false},
+		{"Keybase", "keybase", &e.Keybase, false},
+		{"Twitter", "twitter", &e.Twitter, false},
+		{"Description", "description", &e.Description, true},
+		{"Logo", "logo", &e.Logo, true},
+		{"Country", "country", &e.Country, true},
+		{"Discord", "discord", &e.Discord, true},
+		{"Telegram", "telegram", &e.Telegram, true},
+		{"GitHub", "github", &e.GitHub, true},
+		{"CommissionPolicy", "commission_policy", &e.CommissionPolicy, true},
+	}
+}
+
+// fieldErrors returns the validation errors of the given entity metadata field.
+func fieldErrors(e *registry.EntityMetadata, field string) registry.ValidationErrors {
+	var errs registry.ValidationErrors
+	for _, fieldErr := range e.ValidateFields() {
+		if fieldErr.Field == field {
+			errs = append(errs, fieldErr)
+		}
+	}
+	return errs
+}
+
+// promptEntityField prompts for the value of the given entity metadata field until a valid value
+// is given. An empty answer keeps the current value.
+func promptEntityField(r *bufio.Reader, e *registry.EntityMetadata, f editableEntityField) error {
+	for {
+		fmt.Printf("%s [%s]: ", f.name, *f.value)
+		answer, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.field, err)
+		}
+
+		previous := *f.value
+		switch answer = strings.TrimSpace(answer); answer {
+		case "":
+		case clearFieldValue:
+			*f.value = ""
+		default:
+			*f.value = answer
+		}
+
+		errs := fieldErrors(e, f.field)
+		if len(errs) == 0 {
+			return nil
+		}
+		for _, fieldErr := range errs {
+			fmt.Printf("  Invalid %s: %s\n", f.field, fieldErr)
+		}
+		*f.value = previous
+	}
+}
+
+func doEntityEdit(cmd *cobra.Command, args []string) {
+	p := newFsProvider()
+
+	// Get the signer.
+	signer, err := loadSigner(signature.SignerEntity)
+	if err != nil {
+		logErrorAndExit("failed to load signer", err)
+	}
+
+	// Start from the existing entity metadata (if any).
+	meta := registry.EntityMetadata{
+		Versioned: cbor.NewVersioned(registry.MaxSupportedVersion),
+	}
+	existing, err := p.GetEntity(context.Background(), signer.Public())
+	switch {
+	case err == nil:
+		meta = *existing
+	case errors.Is(err, registry.ErrNoSuchEntity):
+	default:
+		logErrorAndExit("failed to get existing entity metadata", err)
+	}
+	meta.Serial++
+
+	fmt.Printf("Editing the metadata of entity %s (serial: %d).\n", signer.Public(), meta.Serial)
+	fmt.Printf("Press enter to keep the current value or enter '%s' to clear it.\n\n", clearFieldValue)
+
+	r := bufio.NewReader(os.Stdin)
+	for _, field := range editableEntityFields(&meta) {
+		if err = promptEntityField(r, &meta, field); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("editing aborted: %w", err)
+			}
+			logErrorAndExit("failed to edit metadata", err)
+		}
+		if field.extended && *field.value != "" && meta.V < registry.ExtendedFieldsVersion {
+			meta.V = registry.ExtendedFieldsVersion
+		}
+	}
+	fmt.Println()
+
+	validateStatement(entityLogger, registry.EntityStatementKind, &meta)
+	_, signed := confirmAndSignStatement(entityLogger, registry.EntityStatementKind, signer, &meta, os.Stdout)
+
+	if err = p.UpdateStatement(context.Background(), registry.EntityStatementKind, signed); err != nil {
+		logErrorAndExit("failed to update metadata", err)
+	}
+
+	fmt.Printf("Updated entity %s\n", signer.Public())
+}
+
+func init() { //nolint:gochecknoinits
+	entityEditCmd.Flags().AddFlagSet(signFlags)
+
+	entityCmd.AddCommand(entityEditCmd)
+}
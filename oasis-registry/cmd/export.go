@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+// cfgExportStatic configures the directory the static mirror is exported to.
+const cfgExportStatic = "static"
+
+var (
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "export the registry as a static mirror",
+		Args:  cobra.NoArgs,
+		Run:   doExport,
+	}
+
+	exportFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	exportLogger = logging.GetLogger("cmd/export")
+)
+
+func doExport(cmd *cobra.Command, args []string) {
+	dir := viper.GetString(cfgExportStatic)
+	if dir == "" {
+		exportLogger.Error("missing static mirror directory (use --static)")
+		os.Exit(1)
+	}
+
+	index, err := export(dir)
+	if err != nil {
+		exportLogger.Error("failed to export registry",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	var count int
+	for _, entries := range index.Statements {
+		count += len(entries)
+	}
+	fmt.Printf("Exported %d statements to %s\n", count, dir)
+	fmt.Printf("  Revision:   %s\n", index.Revision)
+	fmt.Printf("  Index hash: %s\n", index.Hash)
+	fmt.Println("Pin the index hash in clients to reject stale or modified mirrors.")
+}
+
+// export exports the registry as a static mirror to the given directory.
+func export(dir string) (*registry.Index, error) {
+	p, stop := newQueryProvider(gitConfigFromFlags())
+	defer stop()
+
+	return registry.ExportStaticPath(context.Background(), p, dir)
+}
+
+func init() { //nolint:gochecknoinits
+	exportFlags.String(cfgExportStatic, "", "directory to export a static mirror of the registry to")
+	_ = viper.BindPFlags(exportFlags)
+
+	exportCmd.Flags().AddFlagSet(exportFlags)
+	exportCmd.Flags().AddFlagSet(queryFlags)
+	exportCmd.Flags().AddFlagSet(gitFlags)
+}
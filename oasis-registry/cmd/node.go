@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+var (
+	nodeCmd = &cobra.Command{
+		Use:   "node",
+		Short: "node-related subcommands",
+	}
+
+	nodeUpdateCmd = &cobra.Command{
+		Use:   "update <node-metadata.json>",
+		Short: "update (or create) a node in the registry",
+		Args:  cobra.ExactArgs(1),
+		Run:   doNodeUpdate,
+	}
+
+	nodeLogger = logging.GetLogger("cmd/node")
+)
+
+func doNodeUpdate(cmd *cobra.Command, args []string) {
+	role, err := signerRoleFromFlags(signature.SignerNode)
+	if err != nil {
+		nodeLogger.Error("failed to configure signer",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	updateStatement(nodeLogger, registry.NodeStatementKind, role, args[0], nil)
+}
+
+func init() { //nolint:gochecknoinits
+	nodeUpdateCmd.Flags().AddFlagSet(signerRoleFlags)
+	nodeUpdateCmd.Flags().AddFlagSet(signFlags)
+
+	// Register all of the sub-commands.
+	nodeCmd.AddCommand(nodeUpdateCmd)
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/spf13/cobra"
@@ -12,7 +15,11 @@ import (
 	registry "github.com/oasisprotocol/metadata-registry-tools"
 )
 
-const cfgUpdate = "update"
+const (
+	cfgUpdate    = "update"
+	cfgUpdateRev = "update-rev"
+	cfgRange     = "range"
+)
 
 var (
 	initCmd = &cobra.Command{
@@ -32,7 +39,7 @@ var (
 	registryLogger = logging.GetLogger("cmd/registry")
 )
 
-func newFsProvider() registry.MutableProvider {
+func newFsProvider() registry.MutableStatementProvider {
 	wd, err := os.Getwd()
 	if err != nil {
 		registryLogger.Error("failed to get current working directory",
@@ -41,7 +48,9 @@ func newFsProvider() registry.MutableProvider {
 		os.Exit(1)
 	}
 
-	p, err := registry.NewFilesystemPathProvider(wd)
+	p, err := registry.NewFilesystemPathProviderWithConfig(wd, registry.FilesystemConfig{
+		RuntimeOwners: runtimeOwnersFromFlagsOrExit(),
+	})
 	if err != nil {
 		registryLogger.Error("failed to create filesystem provider",
 			"err", err,
@@ -52,6 +61,19 @@ func newFsProvider() registry.MutableProvider {
 	return p
 }
 
+// runtimeOwnersFromFlagsOrExit returns the runtime owner lookup configured by the flags and exits
+// in case the flags are malformed.
+func runtimeOwnersFromFlagsOrExit() registry.RuntimeOwnerLookup {
+	owners, err := runtimeOwnersFromFlags()
+	if err != nil {
+		registryLogger.Error("failed to configure runtime owners",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	return owners
+}
+
 func doInit(cmd *cobra.Command, args []string) {
 	p := newFsProvider()
 
@@ -65,9 +87,51 @@ func doInit(cmd *cobra.Command, args []string) {
 	fmt.Printf("Initialized metadata registry in %s\n", p.BaseDir())
 }
 
+// updateSource returns the previous registry snapshot to verify the update from (if configured).
+func updateSource(p registry.MutableProvider) (registry.Provider, []interface{}) {
+	var (
+		src registry.Provider
+		err error
+	)
+	switch updateFrom, updateRev := viper.GetString(cfgUpdate), viper.GetString(cfgUpdateRev); {
+	case updateFrom != "" && updateRev != "":
+		registryLogger.Error("only one of --update and --update-rev may be given")
+		os.Exit(1)
+	case updateFrom != "":
+		if src, err = registry.NewFilesystemPathProvider(updateFrom); err != nil {
+			registryLogger.Error("failed to create filesystem provider for source registry",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		return src, []interface{}{"src", updateFrom}
+	case updateRev != "":
+		if src, err = registry.NewGitRevisionProvider(p.BaseDir(), updateRev); err != nil {
+			registryLogger.Error("failed to create Git provider for source registry",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		return src, []interface{}{"rev", updateRev}
+	}
+	return nil, nil
+}
+
 func doVerify(cmd *cobra.Command, args []string) {
 	p := newFsProvider()
 
+	switch format := viper.GetString(cfgFormat); format {
+	case formatText:
+	case formatJSON:
+		doVerifyReport(p)
+		return
+	default:
+		registryLogger.Error("unsupported output format",
+			"format", format,
+		)
+		os.Exit(1)
+	}
+
 	if err := p.Verify(); err != nil {
 		registryLogger.Error("registry integrity verification failed",
 			"err", err,
@@ -75,25 +139,76 @@ func doVerify(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	updateFrom := viper.GetString(cfgUpdate)
-	if updateFrom == "" {
+	if updateRange := viper.GetString(cfgRange); updateRange != "" {
+		verifyRange(p, updateRange)
+	}
+
+	src, srcDesc := updateSource(p)
+	if src == nil {
 		return
 	}
 
-	registryLogger.Info("verifying update from a previous registry snapshot",
-		"src", updateFrom,
-	)
+	registryLogger.Info("verifying update from a previous registry snapshot", srcDesc...)
 
-	src, err := registry.NewFilesystemPathProvider(updateFrom)
+	if err := p.VerifyUpdate(src); err != nil {
+		registryLogger.Error("update integrity verification failed",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doVerifyReport(p registry.MutableStatementProvider) {
+	if viper.GetString(cfgRange) != "" {
+		registryLogger.Error("revision range verification is not supported with JSON output")
+		os.Exit(1)
+	}
+
+	src, _ := updateSource(p)
+	report, err := p.VerifyWithReport(context.Background(), src)
 	if err != nil {
-		registryLogger.Error("failed to create filesystem provider for source registry",
+		registryLogger.Error("registry integrity verification failed",
 			"err", err,
 		)
 		os.Exit(1)
 	}
 
-	if err = p.VerifyUpdate(src); err != nil {
-		registryLogger.Error("update integrity verification failed",
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		registryLogger.Error("failed to marshal verification report",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Printf("%s\n", out)
+
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
+
+func verifyRange(p registry.MutableProvider, updateRange string) {
+	base, head, ok := strings.Cut(updateRange, "..")
+	if !ok || base == "" {
+		registryLogger.Error("malformed revision range, expected <base>..<head>",
+			"range", updateRange,
+		)
+		os.Exit(1)
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+
+	registryLogger.Info("verifying all commits in a revision range",
+		"base", base,
+		"head", head,
+	)
+
+	opts := &registry.VerifyOptions{
+		RuntimeOwners: runtimeOwnersFromFlagsOrExit(),
+	}
+	if err := registry.VerifyGitRange(p.BaseDir(), base, head, opts); err != nil {
+		registryLogger.Error("revision range integrity verification failed",
 			"err", err,
 		)
 		os.Exit(1)
@@ -102,8 +217,12 @@ func doVerify(cmd *cobra.Command, args []string) {
 
 func init() { //nolint:gochecknoinits
 	verifyFlags.String(cfgUpdate, "", "verify update from a previous registry snapshot")
+	verifyFlags.String(cfgUpdateRev, "", "verify update from a previous Git revision of the registry")
+	verifyFlags.String(cfgRange, "", "verify all commits in a Git revision range (<base>..<head>)")
 
 	_ = viper.BindPFlags(verifyFlags)
 
 	verifyCmd.Flags().AddFlagSet(verifyFlags)
+	verifyCmd.Flags().AddFlagSet(formatFlags)
+	verifyCmd.Flags().AddFlagSet(runtimeOwnerFlags)
 }
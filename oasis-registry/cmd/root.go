@@ -55,4 +55,8 @@ func init() { //nolint: gochecknoinits
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(verifyCmd)
 	rootCmd.AddCommand(entityCmd)
+	rootCmd.AddCommand(nodeCmd)
+	rootCmd.AddCommand(statementCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(exportCmd)
 }
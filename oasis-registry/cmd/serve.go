@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+	"github.com/oasisprotocol/metadata-registry-tools/api"
+	registryGrpc "github.com/oasisprotocol/metadata-registry-tools/grpc"
+)
+
+const (
+	// cfgServeAddress configures the address the API server listens on.
+	cfgServeAddress = "address"
+	// cfgServeGrpcAddress configures the address the gRPC server listens on.
+	cfgServeGrpcAddress = "grpc-address"
+	// cfgServeRefreshInterval configures the interval at which the Git registry is refreshed.
+	cfgServeRefreshInterval = "refresh-interval"
+
+	// serveTimeout is the timeout for reading request headers and for shutting down the server.
+	serveTimeout = 10 * time.Second
+)
+
+var (
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "serve the registry over an HTTP (and optionally gRPC) API",
+		Args:  cobra.NoArgs,
+		Run:   doServe,
+	}
+
+	serveFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	serveLogger = logging.GetLogger("cmd/serve")
+)
+
+func doServe(cmd *cobra.Command, args []string) {
+	if err := serve(); err != nil {
+		serveLogger.Error("failed to serve registry API",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// serve serves the registry API until interrupted.
+func serve() error {
+	cfg := gitConfigFromFlags()
+	cfg.RefreshInterval = viper.GetDuration(cfgServeRefreshInterval)
+	qp, stop := newQueryProvider(cfg)
+	defer stop()
+
+	// Avoid verifying all statements again on each request. The revision is not cached, as it is
+	// cheap to compute for both Git and local registries and must not lag behind their updates.
+	p := registry.NewCachingProvider(qp, registry.CacheOptions{})
+
+	srv := &http.Server{
+		Addr:              viper.GetString(cfgServeAddress),
+		Handler:           api.NewHandler(p),
+		ReadHeaderTimeout: serveTimeout,
+	}
+
+	grpcSrv, err := serveGrpc(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	shutdownCh := make(chan struct{})
+	go func() {
+		defer close(shutdownCh)
+		<-ctx.Done()
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), serveTimeout)
+		defer shutdownCancel()
+		_ = srv.Shutdown(shutdownCtx)
+		if grpcSrv != nil {
+			// Watch streams only end when clients disconnect, so they are not waited for.
+			grpcSrv.Stop()
+		}
+	}()
+
+	serveLogger.Info("serving registry API",
+		"address", srv.Addr,
+		"revision", p.Revision(),
+	)
+	if err = srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// Wait for the in-flight requests to complete.
+	<-shutdownCh
+	return nil
+}
+
+// serveGrpc starts serving the registry gRPC service in the background in case it is enabled.
+func serveGrpc(p registry.StatementProvider) (*grpc.Server, error) {
+	addr := viper.GetString(cfgServeGrpcAddress)
+	if addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := grpc.NewServer()
+	registryGrpc.RegisterService(srv, p, registryGrpc.ServiceConfig{})
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			serveLogger.Error("gRPC server failed",
+				"err", err,
+			)
+		}
+	}()
+
+	serveLogger.Info("serving registry gRPC service",
+		"address", addr,
+	)
+	return srv, nil
+}
+
+func init() { //nolint:gochecknoinits
+	serveFlags.String(cfgServeAddress, "127.0.0.1:8080", "address to listen on")
+	serveFlags.String(cfgServeGrpcAddress, "", "address to serve the gRPC service on (disabled if empty)")
+	serveFlags.Duration(cfgServeRefreshInterval, time.Minute, "Git registry refresh interval")
+	_ = viper.BindPFlags(serveFlags)
+
+	serveCmd.Flags().AddFlagSet(serveFlags)
+	serveCmd.Flags().AddFlagSet(queryFlags)
+	serveCmd.Flags().AddFlagSet(gitFlags)
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+var (
+	statementCmd = &cobra.Command{
+		Use:   "statement",
+		Short: "subcommands for statements of any supported kind",
+	}
+
+	statementKindsCmd = &cobra.Command{
+		Use:   "kinds",
+		Short: "list all supported statement kinds",
+		Args:  cobra.NoArgs,
+		Run:   doStatementKinds,
+	}
+
+	statementUpdateCmd = &cobra.Command{
+		Use:   "update <kind> <metadata.json>",
+		Short: "update (or create) a statement of the given kind in the registry",
+		Args:  cobra.ExactArgs(2),
+		Run:   doStatementUpdate,
+	}
+
+	statementLogger = logging.GetLogger("cmd/statement")
+)
+
+// statementPreparer is a hook adjusting the statement signed by signer before it is shown for
+// confirmation.
+type statementPreparer func(signer signature.PublicKey, stmt registry.Statement)
+
+// fieldsValidator is a statement which can report all of its field validation errors.
+type fieldsValidator interface {
+	ValidateFields() registry.ValidationErrors
+}
+
+func doStatementKinds(cmd *cobra.Command, args []string) {
+	for _, kind := range registry.StatementKinds() {
+		fmt.Printf("%s (directory: %s)\n", kind.Name, kind.Dir)
+	}
+}
+
+func doStatementUpdate(cmd *cobra.Command, args []string) {
+	kind, err := registry.StatementKindByName(args[0])
+	if err != nil {
+		statementLogger.Error("unsupported statement kind",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	role, err := signerRoleFromFlags(signature.SignerEntity)
+	if err != nil {
+		statementLogger.Error("failed to configure signer",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	updateStatement(statementLogger, kind, role, args[1], nil)
+}
+
+// validateStatement validates the given statement and exits in case it is invalid.
+func validateStatement(logger *logging.Logger, kind *registry.StatementKind, stmt registry.Statement) {
+	v, ok := stmt.(fieldsValidator)
+	if !ok {
+		if err := stmt.ValidateBasic(); err != nil {
+			logger.Error(fmt.Sprintf("provided %s metadata is invalid", kind.Name),
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if errs := v.ValidateFields(); len(errs) > 0 {
+		for _, fieldErr := range errs {
+			logger.Error(fmt.Sprintf("invalid %s metadata field", kind.Name),
+				"field", fieldErr.Field,
+				"rule", fieldErr.Rule,
+				"err", fieldErr,
+			)
+		}
+		logger.Error(fmt.Sprintf("provided %s metadata is invalid", kind.Name),
+			"err", errs,
+		)
+		os.Exit(1)
+	}
+}
+
+// readStatement reads and parses the unsigned statement of the given kind from the given file and
+// exits in case it cannot be parsed.
+func readStatement(logger *logging.Logger, kind *registry.StatementKind, filename string) registry.Statement {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		logger.Error(fmt.Sprintf("failed to read %s descriptor", kind.Name),
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	stmt := kind.New()
+	if err = json.Unmarshal(raw, stmt); err != nil {
+		logger.Error(fmt.Sprintf("failed to parse serialized %s metadata", kind.Name),
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	return stmt
+}
+
+// signStatement signs the statement of the given kind read from the given file. The statement is
+// adjusted by the optional prepare hook and shown on the given writer before asking for
+// confirmation.
+func signStatement(
+	logger *logging.Logger,
+	kind *registry.StatementKind,
+	role signature.SignerRole,
+	filename string,
+	prepare statementPreparer,
+	w io.Writer,
+) (registry.StatementID, *signature.Signed) {
+	stmt := readStatement(logger, kind, filename)
+	if !viper.GetBool(cfgSkipValidation) {
+		validateStatement(logger, kind, stmt)
+	}
+
+	// Get the signer.
+	signer, err := loadSigner(role)
+	if err != nil {
+		logger.Error("failed to load signer",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	if prepare != nil {
+		prepare(signer.Public(), stmt)
+	}
+	return confirmAndSignStatement(logger, kind, signer, stmt, w)
+}
+
+// confirmAndSignStatement shows the statement of the given kind on the given writer, asks for
+// confirmation and signs it.
+func confirmAndSignStatement(
+	logger *logging.Logger,
+	kind *registry.StatementKind,
+	signer signature.Signer,
+	stmt registry.Statement,
+	w io.Writer,
+) (registry.StatementID, *signature.Signed) {
+	// Show descriptor and ask for confirmation.
+	fmt.Fprintf(w, "You are about to sign the following %s metadata descriptor:\n", kind.Name)
+	stmt.PrettyPrint(context.Background(), "  ", w)
+	confirmSigning(w)
+
+	// Sign the descriptor.
+	signed, err := kind.Sign(signer, stmt)
+	if err != nil {
+		logger.Error("failed to sign metadata",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	return kind.ID(signer.Public(), stmt), signed
+}
+
+// updateStatement signs the statement of the given kind read from the given file and stores it in
+// the registry in the current working directory. The statement is adjusted by the optional prepare
+// hook before signing.
+func updateStatement(
+	logger *logging.Logger,
+	kind *registry.StatementKind,
+	role signature.SignerRole,
+	filename string,
+	prepare statementPreparer,
+) {
+	p := newFsProvider()
+
+	id, signed := signStatement(logger, kind, role, filename, prepare, os.Stdout)
+	if err := p.UpdateStatement(context.Background(), kind, signed); err != nil {
+		logger.Error("failed to update metadata",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Updated %s %s\n", kind.Name, id)
+}
+
+func init() { //nolint:gochecknoinits
+	statementUpdateCmd.Flags().AddFlagSet(signerRoleFlags)
+	statementUpdateCmd.Flags().AddFlagSet(signFlags)
+	statementUpdateCmd.Flags().AddFlagSet(runtimeOwnerFlags)
+
+	// Register all of the sub-commands.
+	statementCmd.AddCommand(statementKindsCmd)
+	statementCmd.AddCommand(statementUpdateCmd)
+}
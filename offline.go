@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// SigningRequest is an unsigned statement prepared for signing by an offline (e.g. air-gapped)
+// signer.
+type SigningRequest struct {
+	// Kind is the name of the statement kind.
+	Kind string `json:"kind"`
+
+	// SignatureContext is the domain separation context that must be used for signing.
+	SignatureContext signature.Context `json:"signature_context"`
+
+	// Payload is the exact CBOR-encoded statement that must be signed.
+	Payload []byte `json:"payload"`
+
+	// Digest is the hex-encoded digest of the signature context and the payload, which is the
+	// message actually signed by the Ed25519 key.
+	Digest string `json:"digest"`
+}
+
+// NewSigningRequest prepares the statement of this kind for signing by an offline signer.
+func (k *StatementKind) NewSigningRequest(stmt Statement) (*SigningRequest, error) {
+	payload := cbor.Marshal(stmt)
+	digest, err := signature.PrepareSignerMessage(k.SignatureContext, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s metadata for signing: %w", k.Name, err)
+	}
+
+	return &SigningRequest{
+		Kind:             k.Name,
+		SignatureContext: k.SignatureContext,
+		Payload:          payload,
+		Digest:           hex.EncodeToString(digest),
+	}, nil
+}
+
+// PrepareEntityMetadata prepares the EntityMetadata for signing by an offline signer.
+func PrepareEntityMetadata(meta *EntityMetadata) (*SigningRequest, error) {
+	return EntityStatementKind.NewSigningRequest(meta)
+}
+
+// AttachEntityMetadataSignature combines the EntityMetadata with a raw signature produced by an
+// offline signer. The resulting signed statement is verified before it is returned.
+func AttachEntityMetadataSignature(
+	meta *EntityMetadata,
+	signer signature.PublicKey,
+	sig signature.RawSignature,
+) (*SignedEntityMetadata, error) {
+	signed := &SignedEntityMetadata{
+		Signed: signature.Signed{
+			Blob: cbor.Marshal(meta),
+			Signature: signature.Signature{
+				PublicKey: signer,
+				Signature: sig,
+			},
+		},
+	}
+
+	var opened EntityMetadata
+	if err := signed.Open(&opened); err != nil {
+		return nil, newStatementError(ErrBadSignature, fmt.Errorf("bad signed entity metadata: %w", err))
+	}
+	if err := opened.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("bad signed entity metadata: %w", err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineSigning(t *testing.T) {
+	require := require.New(t)
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	meta := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "My entity name",
+	}
+
+	req, err := PrepareEntityMetadata(meta)
+	require.NoError(err, "PrepareEntityMetadata")
+	require.Equal(EntityStatementKind.Name, req.Kind)
+	require.Equal(EntityMetadataSignatureContext, req.SignatureContext)
+	require.Equal(cbor.Marshal(meta), req.Payload)
+
+	// Signing the digest directly must be equivalent to signing the payload in context.
+	digest, err := hex.DecodeString(req.Digest)
+	require.NoError(err, "DecodeString")
+	privKey := ed25519.PrivateKey(signer.(signature.UnsafeSigner).UnsafeBytes())
+	var rawSig signature.RawSignature
+	copy(rawSig[:], ed25519.Sign(privKey, digest))
+
+	signed, err := AttachEntityMetadataSignature(meta, signer.Public(), rawSig)
+	require.NoError(err, "AttachEntityMetadataSignature")
+	expected, err := SignEntityMetadata(signer, meta)
+	require.NoError(err, "SignEntityMetadata")
+	require.EqualValues(expected, signed)
+
+	// Signatures by other keys or over other metadata must be rejected.
+	_, err = AttachEntityMetadataSignature(meta, other.Public(), rawSig)
+	require.True(errors.Is(err, ErrBadSignature), "AttachEntityMetadataSignature should fail for wrong signer")
+
+	changed := *meta
+	changed.Serial = 2
+	_, err = AttachEntityMetadataSignature(&changed, signer.Public(), rawSig)
+	require.True(errors.Is(err, ErrBadSignature), "AttachEntityMetadataSignature should fail for changed metadata")
+
+	// Invalid metadata must be rejected even if properly signed.
+	invalid := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		Name:      "My entity name",
+		URL:       "not a url",
+	}
+	req, err = PrepareEntityMetadata(invalid)
+	require.NoError(err, "PrepareEntityMetadata")
+	sig, err := signer.ContextSign(req.SignatureContext, req.Payload)
+	require.NoError(err, "ContextSign")
+	copy(rawSig[:], sig)
+	_, err = AttachEntityMetadataSignature(invalid, signer.Public(), rawSig)
+	require.Error(err, "AttachEntityMetadataSignature should fail for invalid metadata")
+}
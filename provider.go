@@ -0,0 +1,279 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// statementProvider is the subset of the Provider interface which the remaining Provider methods
+// can be implemented on top of.
+type statementProvider interface {
+	GetStatements(ctx context.Context, kind *StatementKind) (map[StatementID]Statement, error)
+	GetStatement(ctx context.Context, kind *StatementKind, id StatementID) (Statement, error)
+	GetSignedStatement(ctx context.Context, kind *StatementKind, id StatementID) (*SignedStatement, error)
+}
+
+// unsignedStatements returns the verified statements of the given signed statements.
+func unsignedStatements(signed map[StatementID]*SignedStatement) map[StatementID]Statement {
+	stmts := make(map[StatementID]Statement, len(signed))
+	for id, s := range signed {
+		stmts[id] = s.Statement
+	}
+	return stmts
+}
+
+// numWorkers returns the number of workers to use for loading statements in parallel given the
+// configured number of workers (where zero means the default).
+func numWorkers(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// forEachParallel calls fn for each index in [0, n) using at most the given number of concurrent
+// workers. No further calls are made after a call fails. Workers check the context before each
+// call, so once it is done, no further calls are made and the context error is returned.
+//
+// In case any calls fail, the error of the failed call with the lowest index is returned, which is
+// the same error as if the calls were made sequentially.
+func forEachParallel(ctx context.Context, workers, n int, fn func(i int) error) error {
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Bool
+	)
+	errs := make([]error, n)
+	indexCh := make(chan int)
+	for w := 0; w < min(numWorkers(workers), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				if errs[i] = ctx.Err(); errs[i] == nil {
+					errs[i] = fn(i)
+				}
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	// Calls with lower indices are always dispatched first, so they have completed by the time
+	// all workers are done.
+	err := ctx.Err()
+	for i := 0; i < n && err == nil && !failed.Load(); i++ {
+		select {
+		case indexCh <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(indexCh)
+	wg.Wait()
+
+	for _, callErr := range errs {
+		if callErr != nil {
+			return callErr
+		}
+	}
+	return err
+}
+
+// verifyStatements verifies the integrity of all statements served by p.
+func verifyStatements(ctx context.Context, p statementProvider) error {
+	for _, kind := range StatementKinds() {
+		if _, err := p.GetStatements(ctx, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyStatementsUpdates verifies the integrity of a registry update of all statements served
+// by p from src.
+func verifyStatementsUpdates(ctx context.Context, p statementProvider, src Provider, opts *VerifyOptions) error {
+	for _, kind := range StatementKinds() {
+		if err := verifyStatementsUpdate(ctx, p, kind, src, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyStatementsUpdate verifies the integrity of a registry update of statements of the given
+// kind from src.
+func verifyStatementsUpdate(
+	ctx context.Context,
+	p statementProvider,
+	kind *StatementKind,
+	src Provider,
+	opts *VerifyOptions,
+) error {
+	dstStmts, err := p.GetStatements(ctx, kind)
+	if err != nil {
+		return fmt.Errorf("destination registry is corrupted: %w", err)
+	}
+
+	srcStmts, err := getSourceStatements(ctx, src, kind)
+	if err != nil {
+		return fmt.Errorf("source registry is corrupted: %w", err)
+	}
+
+	// No statements can be removed by an update.
+	for id := range srcStmts {
+		if dstStmts[id] == nil {
+			return newStatementError(kind.ErrRemoved, fmt.Errorf("%s statement has been removed: %s", kind.Name, id))
+		}
+	}
+
+	// Updated statements must use a higher serial number.
+	for id, dst := range dstStmts {
+		src := srcStmts[id]
+		switch {
+		case src == nil:
+			if err = verifyStatementCreate(ctx, p, kind, id, dst, opts); err != nil {
+				return fmt.Errorf("new %s '%s': %w", kind.Name, id, err)
+			}
+		case statementsEqual(src, dst):
+			// Unchanged statement.
+		default:
+			if err = verifyStatementUpdate(ctx, p, kind, id, src, dst); err != nil {
+				return fmt.Errorf("updated %s '%s': %w", kind.Name, id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getSourceStatements returns all statements of the given kind served by the source registry of an
+// update. Providers not implementing StatementProvider only serve the entities returned by
+// GetEntities.
+func getSourceStatements(ctx context.Context, src Provider, kind *StatementKind) (map[StatementID]Statement, error) {
+	if sp, ok := src.(StatementProvider); ok {
+		return sp.GetStatements(ctx, kind)
+	}
+
+	stmts := make(map[StatementID]Statement)
+	if kind != EntityStatementKind {
+		return stmts, nil
+	}
+	entities, err := src.GetEntities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for id, entity := range entities {
+		stmts[id] = entity
+	}
+	return stmts, nil
+}
+
+// verifyStatementCreate verifies that the new statement stmt served by p may be added to the
+// registry.
+func verifyStatementCreate(
+	ctx context.Context,
+	p statementProvider,
+	kind *StatementKind,
+	id StatementID,
+	stmt Statement,
+	opts *VerifyOptions,
+) error {
+	if kind.VerifyCreate == nil {
+		return nil
+	}
+
+	signed, err := p.GetSignedStatement(ctx, kind, id)
+	if err != nil {
+		return fmt.Errorf("destination registry is corrupted: %w", err)
+	}
+	return kind.verifyCreate(ctx, opts, stmt, signed.Signed.Signature.PublicKey)
+}
+
+// verifyStatementUpdate verifies that the statement dst served by p is a valid update of the
+// existing statement src.
+func verifyStatementUpdate(
+	ctx context.Context,
+	p statementProvider,
+	kind *StatementKind,
+	id StatementID,
+	src, dst Statement,
+) error {
+	signed, err := p.GetSignedStatement(ctx, kind, id)
+	if err != nil {
+		return fmt.Errorf("destination registry is corrupted: %w", err)
+	}
+	return kind.verifyUpdate(src, dst, signed.Signed.Signature.PublicKey)
+}
+
+// getEntities returns all entities served by p, skipping entities which have revoked their
+// metadata.
+func getEntities(ctx context.Context, p statementProvider) (map[signature.PublicKey]*EntityMetadata, error) {
+	stmts, err := p.GetStatements(ctx, EntityStatementKind)
+	if err != nil {
+		return nil, err
+	}
+	entities := typedStatements[signature.PublicKey, *EntityMetadata](stmts)
+
+	// Skip entities which have revoked their metadata.
+	for id, entity := range entities {
+		if entity.Revoked {
+			delete(entities, id)
+		}
+	}
+	return entities, nil
+}
+
+// getEntity returns metadata for a specific entity served by p.
+func getEntity(ctx context.Context, p statementProvider, id signature.PublicKey) (*EntityMetadata, error) {
+	stmt, err := p.GetStatement(ctx, EntityStatementKind, id)
+	entity, _ := stmt.(*EntityMetadata)
+	return checkEntityRevoked(entity, err)
+}
+
+// checkEntityRevoked returns ErrEntityRevoked together with the revocation tombstone in case the
+// successfully loaded entity metadata has been revoked.
+func checkEntityRevoked(entity *EntityMetadata, err error) (*EntityMetadata, error) {
+	if err == nil && entity.Revoked {
+		return entity, ErrEntityRevoked
+	}
+	return entity, err
+}
+
+// getNodes returns all nodes served by p.
+func getNodes(ctx context.Context, p statementProvider) (map[signature.PublicKey]*NodeMetadata, error) {
+	stmts, err := p.GetStatements(ctx, NodeStatementKind)
+	if err != nil {
+		return nil, err
+	}
+	return typedStatements[signature.PublicKey, *NodeMetadata](stmts), nil
+}
+
+// getNode returns metadata for a specific node served by p.
+func getNode(ctx context.Context, p statementProvider, id signature.PublicKey) (*NodeMetadata, error) {
+	stmt, err := p.GetStatement(ctx, NodeStatementKind, id)
+	node, _ := stmt.(*NodeMetadata)
+	return node, err
+}
+
+// getRuntimes returns all runtimes served by p.
+func getRuntimes(ctx context.Context, p statementProvider) (map[common.Namespace]*RuntimeMetadata, error) {
+	stmts, err := p.GetStatements(ctx, RuntimeStatementKind)
+	if err != nil {
+		return nil, err
+	}
+	return typedStatements[common.Namespace, *RuntimeMetadata](stmts), nil
+}
+
+// getRuntime returns metadata for a specific runtime served by p.
+func getRuntime(ctx context.Context, p statementProvider, id common.Namespace) (*RuntimeMetadata, error) {
+	stmt, err := p.GetStatement(ctx, RuntimeStatementKind, id)
+	runtime, _ := stmt.(*RuntimeMetadata)
+	return runtime, err
+}
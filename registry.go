@@ -4,17 +4,14 @@ package registry
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"net/mail"
-	"net/url"
 	"regexp"
 
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
-	"github.com/oasisprotocol/oasis-core/go/common/prettyprint"
 )
 
 var (
@@ -24,6 +21,33 @@ var (
 	// ErrCorruptedRegistry is the error returned where the registry is corrupted (does not conform
 	// to the specifications or contains data that fails signature verification).
 	ErrCorruptedRegistry = errors.New("registry: corrupted registry")
+
+	// ErrBadFilename is the error returned where a statement filename is malformed.
+	ErrBadFilename = errors.New("registry: bad statement filename")
+
+	// ErrStatementTooBig is the error returned where a statement exceeds MaxStatementSize.
+	ErrStatementTooBig = errors.New("registry: statement too big")
+
+	// ErrMalformedStatement is the error returned where a statement cannot be read or decoded.
+	ErrMalformedStatement = errors.New("registry: malformed statement")
+
+	// ErrSignerMismatch is the error returned where a statement is not signed by the expected
+	// signer.
+	ErrSignerMismatch = errors.New("registry: signer mismatch")
+
+	// ErrBadSignature is the error returned where statement signature verification fails.
+	ErrBadSignature = errors.New("registry: bad signature")
+
+	// ErrSerialNotIncreased is the error returned where an updated statement does not increase
+	// the serial number.
+	ErrSerialNotIncreased = errors.New("registry: serial number not increased")
+
+	// ErrEntityRemoved is the error returned where an entity statement has been removed by an
+	// update.
+	ErrEntityRemoved = errors.New("registry: entity removed")
+
+	// ErrEntityRevoked is the error returned where the requested entity has revoked its metadata.
+	ErrEntityRevoked = errors.New("registry: entity revoked")
 )
 
 const (
@@ -40,11 +64,31 @@ const (
 	MaxEntityKeybaseLength = 32
 	// MaxEntityTwitterLength is the maximum length of the entity metadata's Twitter field.
 	MaxEntityTwitterLength = 32
+	// MaxEntityDescriptionLength is the maximum length of the entity metadata's Description field.
+	MaxEntityDescriptionLength = 256
+	// MaxEntityLogoLength is the maximum length of the entity metadata's Logo field.
+	MaxEntityLogoLength = 128
+	// MaxEntityDiscordLength is the maximum length of the entity metadata's Discord field.
+	MaxEntityDiscordLength = 32
+	// MaxEntityTelegramLength is the maximum length of the entity metadata's Telegram field.
+	MaxEntityTelegramLength = 32
+	// MaxEntityGitHubLength is the maximum length of the entity metadata's GitHub field.
+	MaxEntityGitHubLength = 39
+	// MaxEntityCommissionPolicyLength is the maximum length of the entity metadata's
+	// CommissionPolicy field.
+	MaxEntityCommissionPolicyLength = 256
+	// MaxEntityRevocationReasonLength is the maximum length of the entity metadata's
+	// RevocationReason field.
+	MaxEntityRevocationReasonLength = 256
 
 	// MinSupportedVersion is the minimum supported entity metadata version.
 	MinSupportedVersion = 1
 	// MaxSupportedVersion is the maximum supported entity metadata version.
-	MaxSupportedVersion = 1
+	MaxSupportedVersion = 2
+
+	// ExtendedFieldsVersion is the minimum entity metadata version supporting the extended
+	// fields (Description, Logo, Country, Discord, Telegram, GitHub and CommissionPolicy).
+	ExtendedFieldsVersion = 2
 )
 
 var (
@@ -52,27 +96,133 @@ var (
 	TwitterHandleRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
 	// KeybaseHandleRegexp is the regular expression used for validating the Keybase field.
 	KeybaseHandleRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// CountryCodeRegexp is the regular expression used for validating the Country field.
+	CountryCodeRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+	// DiscordHandleRegexp is the regular expression used for validating the Discord field.
+	DiscordHandleRegexp = regexp.MustCompile(`^[a-z0-9_.]+$`)
+	// TelegramHandleRegexp is the regular expression used for validating the Telegram field.
+	TelegramHandleRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// GitHubHandleRegexp is the regular expression used for validating the GitHub field.
+	GitHubHandleRegexp = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
 )
 
 // Provider is the read-only registry provider interface.
+//
+// All providers created by this package additionally implement StatementProvider.
 type Provider interface {
 	// Verify verifies the integrity of the whole registry.
 	Verify() error
 
 	// VerifyUpdate verifies the integrity of a registry update from src.
+	//
+	// In case src does not implement StatementProvider, only the entities it returns from
+	// GetEntities are considered.
 	VerifyUpdate(src Provider) error
 
-	// GetEntities returns a list of all entities in the registry.
+	// GetEntities returns a list of all entities in the registry. Entities which have revoked
+	// their metadata are skipped.
 	GetEntities(ctx context.Context) (map[signature.PublicKey]*EntityMetadata, error)
 
 	// GetEntity returns metadata for a specific entity.
+	//
+	// In case the entity has revoked its metadata, ErrEntityRevoked is returned together with
+	// the revocation tombstone.
 	GetEntity(ctx context.Context, id signature.PublicKey) (*EntityMetadata, error)
 }
 
+// StatementProvider is a read-only registry provider serving statements of all kinds, not just
+// entity metadata. It is implemented by all providers created by this package, but is kept
+// separate from Provider so that existing Provider implementations remain valid.
+type StatementProvider interface {
+	Provider
+
+	// GetNodes returns a list of all nodes in the registry.
+	GetNodes(ctx context.Context) (map[signature.PublicKey]*NodeMetadata, error)
+
+	// GetNode returns metadata for a specific node.
+	GetNode(ctx context.Context, id signature.PublicKey) (*NodeMetadata, error)
+
+	// GetRuntimes returns a list of all runtimes in the registry.
+	GetRuntimes(ctx context.Context) (map[common.Namespace]*RuntimeMetadata, error)
+
+	// GetRuntime returns metadata for a specific runtime.
+	GetRuntime(ctx context.Context, id common.Namespace) (*RuntimeMetadata, error)
+
+	// GetStatements returns a list of all statements of the given kind in the registry.
+	GetStatements(ctx context.Context, kind *StatementKind) (map[StatementID]Statement, error)
+
+	// GetStatement returns a specific statement of the given kind.
+	GetStatement(ctx context.Context, kind *StatementKind, id StatementID) (Statement, error)
+
+	// GetSignedStatements returns a list of all statements of the given kind in the registry
+	// together with the signed statements they have been loaded from.
+	GetSignedStatements(ctx context.Context, kind *StatementKind) (map[StatementID]*SignedStatement, error)
+
+	// GetSignedStatement returns a specific statement of the given kind together with the signed
+	// statement it has been loaded from. The statement is verified before it is returned.
+	GetSignedStatement(ctx context.Context, kind *StatementKind, id StatementID) (*SignedStatement, error)
+
+	// Revision returns an identifier of the registry revision being served, which changes each
+	// time the registry is updated. An empty string is returned in case it cannot be determined.
+	Revision() string
+
+	// VerifyContext is like Verify, but stops verifying once the given context is done.
+	VerifyContext(ctx context.Context) error
+
+	// VerifyUpdateContext is like VerifyUpdate, but stops verifying once the given context is
+	// done.
+	VerifyUpdateContext(ctx context.Context, src Provider) error
+}
+
+// SnapshotProvider is a registry provider which can serve a consistent snapshot of the registry,
+// so that operations spanning multiple calls (e.g. serving an API request) see all statements and
+// the revision as of a single point in time. It is implemented by the Git and HTTP providers and
+// by caching providers wrapping them.
+type SnapshotProvider interface {
+	StatementProvider
+
+	// Snapshot returns a provider serving the registry as currently served by this provider. The
+	// snapshot is not affected by subsequent registry updates.
+	Snapshot(ctx context.Context) (StatementProvider, error)
+}
+
+// Snapshot returns a snapshot of the registry served by the given provider in case it implements
+// SnapshotProvider. Otherwise the provider itself is returned.
+func Snapshot(ctx context.Context, p StatementProvider) (StatementProvider, error) {
+	if sp, ok := p.(SnapshotProvider); ok {
+		return sp.Snapshot(ctx)
+	}
+	return p, nil
+}
+
 // EntityMetadataSignatureContext is the domain separation context used for entity metadata.
 var EntityMetadataSignatureContext = signature.NewContext("oasis-metadata-registry: entity")
 
-var _ prettyprint.PrettyPrinter = (*EntityMetadata)(nil)
+// EntityStatementKind is the entity metadata statement kind.
+var EntityStatementKind = &StatementKind{
+	Name:               "entity",
+	Dir:                registryEntityDir,
+	SignatureContext:   EntityMetadataSignatureContext,
+	MaxSize:            MaxStatementSize,
+	ErrNoSuchStatement: ErrNoSuchEntity,
+	ErrRemoved:         ErrEntityRemoved,
+	New: func() Statement {
+		return new(EntityMetadata)
+	},
+	ParseFilename: parsePublicKeyFilename,
+	Filename:      publicKeyFilename,
+	ID: func(signer signature.PublicKey, _ Statement) StatementID {
+		return signer
+	},
+	VerifyUpdate: func(src, _ Statement, _ signature.PublicKey) error {
+		if src.(*EntityMetadata).Revoked {
+			return newStatementError(ErrEntityRevoked, fmt.Errorf("revoked entity metadata cannot be updated"))
+		}
+		return nil
+	},
+}
+
+var _ Statement = (*EntityMetadata)(nil)
 
 // EntityMetadata contains metadata about an entity.
 type EntityMetadata struct {
@@ -95,118 +245,131 @@ type EntityMetadata struct {
 
 	// Twitter is the Twitter handle.
 	Twitter string `json:"twitter,omitempty"`
-}
 
-// Equal compares vs another entity metadata for equality.
-func (e *EntityMetadata) Equal(other *EntityMetadata) bool {
-	return bytes.Equal(cbor.Marshal(e), cbor.Marshal(other))
-}
+	// Description is a short description of the entity (version 2+).
+	Description string `json:"description,omitempty"`
 
-// validateURL checks validity of the given URL.
-func validateURL(u string) error {
-	if len(u) > MaxEntityURLLength {
-		return fmt.Errorf("entity URL too long (length: %d max: %d)", len(u), MaxEntityURLLength)
-	}
-	if len(u) > 0 {
-		parsedURL, err := url.Parse(u)
-		if err != nil {
-			return fmt.Errorf("entity URL is malformed: %w", err)
-		}
-		if parsedURL.Scheme != "https" {
-			return fmt.Errorf("entity URL must use the https scheme (scheme: %s)", parsedURL.Scheme)
-		}
-		if port := parsedURL.Port(); port != "" {
-			return fmt.Errorf("entity URL must use the default port (port: %s)", port)
-		}
-		if len(parsedURL.RawQuery) != 0 || len(parsedURL.Fragment) != 0 {
-			return fmt.Errorf("entity URL must not contain query values or fragments")
-		}
-	}
-	return nil
-}
+	// Logo is an URL of the entity's logo or avatar image (version 2+).
+	Logo string `json:"logo,omitempty"`
 
-// ValidateBasic performs basic validity checks on the entity metadata.
-func (e *EntityMetadata) ValidateBasic() error {
-	if e.Versioned.V < MinSupportedVersion || e.Versioned.V > MaxSupportedVersion {
-		return fmt.Errorf("unsupported entity metadata version: %d", e.Versioned.V)
-	}
+	// Country is the ISO 3166-1 alpha-2 code of the country where the entity is located
+	// (version 2+).
+	Country string `json:"country,omitempty"`
 
-	// Name.
-	if len(e.Name) > MaxEntityNameLength {
-		return fmt.Errorf("entity name too long (length: %d max: %d)", len(e.Name), MaxEntityNameLength)
-	}
+	// Discord is the Discord handle (version 2+).
+	Discord string `json:"discord,omitempty"`
 
-	// URL.
-	if err := validateURL(e.URL); err != nil {
-		return err
-	}
+	// Telegram is the Telegram handle (version 2+).
+	Telegram string `json:"telegram,omitempty"`
 
-	// Email.
-	if len(e.Email) > MaxEntityEmailLength {
-		return fmt.Errorf("entity e-mail too long (length: %d max: %d)", len(e.Email), MaxEntityEmailLength)
-	}
-	if len(e.Email) > 0 {
-		parsedEmail, err := mail.ParseAddress(e.Email)
-		if err != nil {
-			return fmt.Errorf("entity e-mail is malformed: %w", err)
-		}
-		if len(parsedEmail.Name) != 0 {
-			return fmt.Errorf("entity e-mail must not contain a name")
-		}
-	}
+	// GitHub is the GitHub handle (version 2+).
+	GitHub string `json:"github,omitempty"`
 
-	// Keybase.
-	if len(e.Keybase) > MaxEntityKeybaseLength {
-		return fmt.Errorf("entity keybase handle too long (length: %d max: %d)", len(e.Keybase), MaxEntityKeybaseLength)
-	}
-	if len(e.Keybase) > 0 {
-		if !KeybaseHandleRegexp.MatchString(e.Keybase) {
-			return fmt.Errorf("entity keybase handle is malformed")
+	// CommissionPolicy are notes on the entity's commission policy (version 2+).
+	CommissionPolicy string `json:"commission_policy,omitempty"`
+
+	// Revoked marks the statement as a revocation tombstone which withdraws all of the entity's
+	// metadata (version 2+). A revoked statement cannot be updated anymore.
+	Revoked bool `json:"revoked,omitempty"`
+
+	// RevocationReason is the (optional) reason for revoking the entity's metadata (version 2+).
+	RevocationReason string `json:"revocation_reason,omitempty"`
+}
+
+// hasExtendedFields returns true iff any of the extended (version 2+) fields is set.
+func (e *EntityMetadata) hasExtendedFields() bool {
+	return e.Description != "" || e.Logo != "" || e.Country != "" || e.Discord != "" ||
+		e.Telegram != "" || e.GitHub != "" || e.CommissionPolicy != "" || e.Revoked || e.RevocationReason != ""
+}
+
+// validateRevocation checks that a revocation tombstone does not contain any metadata and that
+// only revoked metadata contains a revocation reason.
+func (e *EntityMetadata) validateRevocation(v *validator) {
+	if !e.Revoked {
+		if e.RevocationReason != "" {
+			v.formatf("revocation_reason", "entity revocation reason requires revoked entity metadata")
 		}
+		return
 	}
 
-	// Twitter.
-	if len(e.Twitter) > MaxEntityTwitterLength {
-		return fmt.Errorf("entity twitter handle too long (length: %d max: %d)", len(e.Twitter), MaxEntityTwitterLength)
-	}
-	if len(e.Twitter) > 0 {
-		if !TwitterHandleRegexp.MatchString(e.Twitter) {
-			return fmt.Errorf("entity twitter handle is malformed")
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"name", e.Name},
+		{"url", e.URL},
+		{"email", e.Email},
+		{"keybase", e.Keybase},
+		{"twitter", e.Twitter},
+		{"description", e.Description},
+		{"logo", e.Logo},
+		{"country", e.Country},
+		{"discord", e.Discord},
+		{"telegram", e.Telegram},
+		{"github", e.GitHub},
+		{"commission_policy", e.CommissionPolicy},
+	} {
+		if field.value != "" {
+			v.formatf(field.name, "revoked entity metadata must not contain the %s field", field.name)
 		}
 	}
+}
 
-	return nil
+// Equal compares vs another entity metadata for equality.
+func (e *EntityMetadata) Equal(other *EntityMetadata) bool {
+	return bytes.Equal(cbor.Marshal(e), cbor.Marshal(other))
 }
 
-// Load loads and verifies entity metadata from a given reader containing signed entity metadata.
-func (e *EntityMetadata) Load(id signature.PublicKey, r io.Reader) error {
-	b, err := io.ReadAll(r)
-	if err != nil {
-		return fmt.Errorf("%w: failed to read metadata: %s", ErrCorruptedRegistry, err)
-	}
-	if len(b) > MaxStatementSize {
-		return fmt.Errorf("%w: statement too big (size: %d max: %d)", ErrCorruptedRegistry, len(b), MaxStatementSize)
-	}
+// StatementSerial returns the serial number of the entity metadata statement.
+func (e *EntityMetadata) StatementSerial() uint64 {
+	return e.Serial
+}
 
-	var sigEntity SignedEntityMetadata
-	if err = json.Unmarshal(b, &sigEntity); err != nil {
-		return fmt.Errorf("%w: failed to unmarshal signed entity metadata: %s", ErrCorruptedRegistry, err)
-	}
-	if !sigEntity.Signature.PublicKey.Equal(id) {
-		return fmt.Errorf("%w: entity metadata signer does not match expected entity (expected: %s got: %s)",
-			ErrCorruptedRegistry,
-			id,
-			sigEntity.Signature.PublicKey,
-		)
+// ValidateBasic performs basic validity checks on the entity metadata.
+//
+// In case the metadata is invalid, the first validation error is returned. Use ValidateFields to
+// get all of them.
+func (e *EntityMetadata) ValidateBasic() error {
+	if errs := e.ValidateFields(); len(errs) > 0 {
+		return errs[0]
 	}
+	return nil
+}
 
-	if err = sigEntity.Open(e); err != nil {
-		return fmt.Errorf("%w: failed to verify signed entity metadata: %s", ErrCorruptedRegistry, err)
+// ValidateFields performs basic validity checks on the entity metadata and returns all field
+// validation errors.
+func (e *EntityMetadata) ValidateFields() ValidationErrors {
+	var v validator
+	v.version("v", "entity metadata", e.Versioned.V, MinSupportedVersion, MaxSupportedVersion)
+	v.maxLength("name", "entity name", e.Name, MaxEntityNameLength)
+	v.url("url", "entity URL", e.URL, MaxEntityURLLength)
+	v.email("email", "entity e-mail", e.Email, MaxEntityEmailLength)
+	v.handle("keybase", "entity keybase handle", e.Keybase, MaxEntityKeybaseLength, KeybaseHandleRegexp)
+	v.handle("twitter", "entity twitter handle", e.Twitter, MaxEntityTwitterLength, TwitterHandleRegexp)
+
+	// Extended fields.
+	if e.hasExtendedFields() && e.Versioned.V < ExtendedFieldsVersion {
+		v.minVersion("v", "entity metadata extended fields", e.Versioned.V, ExtendedFieldsVersion)
 	}
-	if err = e.ValidateBasic(); err != nil {
-		return fmt.Errorf("%w: failed to validate entity metadata: %s", ErrCorruptedRegistry, err)
-	}
-	return nil
+	v.maxLength("description", "entity description", e.Description, MaxEntityDescriptionLength)
+	v.url("logo", "entity logo URL", e.Logo, MaxEntityLogoLength)
+	v.handle("country", "entity country code", e.Country, len("XX"), CountryCodeRegexp)
+	v.handle("discord", "entity discord handle", e.Discord, MaxEntityDiscordLength, DiscordHandleRegexp)
+	v.handle("telegram", "entity telegram handle", e.Telegram, MaxEntityTelegramLength, TelegramHandleRegexp)
+	v.handle("github", "entity github handle", e.GitHub, MaxEntityGitHubLength, GitHubHandleRegexp)
+	v.maxLength(
+		"commission_policy", "entity commission policy", e.CommissionPolicy, MaxEntityCommissionPolicyLength,
+	)
+	v.maxLength(
+		"revocation_reason", "entity revocation reason", e.RevocationReason, MaxEntityRevocationReasonLength,
+	)
+	e.validateRevocation(&v)
+	return v.errs
+}
+
+// Load loads and verifies entity metadata from a given reader containing signed entity metadata.
+func (e *EntityMetadata) Load(id signature.PublicKey, r io.Reader) error {
+	return EntityStatementKind.load(id, r, e)
 }
 
 // PrettyPrint writes a pretty-printed representation of EntityMetadata to the
@@ -214,11 +377,26 @@ func (e *EntityMetadata) Load(id signature.PublicKey, r io.Reader) error {
 func (e *EntityMetadata) PrettyPrint(ctx context.Context, prefix string, w io.Writer) {
 	fmt.Fprintf(w, "%sVersion: %d\n", prefix, e.V)
 	fmt.Fprintf(w, "%sSerial:  %d\n", prefix, e.Serial)
+	if e.Revoked {
+		// Revocation tombstones contain no other metadata.
+		fmt.Fprintf(w, "%sRevoked: true\n", prefix)
+		fmt.Fprintf(w, "%sReason:  %s\n", prefix, e.RevocationReason)
+		return
+	}
 	fmt.Fprintf(w, "%sName:    %s\n", prefix, e.Name)
 	fmt.Fprintf(w, "%sURL:     %s\n", prefix, e.URL)
 	fmt.Fprintf(w, "%sEmail:   %s\n", prefix, e.Email)
 	fmt.Fprintf(w, "%sKeybase: %s\n", prefix, e.Keybase)
 	fmt.Fprintf(w, "%sTwitter: %s\n", prefix, e.Twitter)
+	if e.V >= ExtendedFieldsVersion {
+		fmt.Fprintf(w, "%sDescription: %s\n", prefix, e.Description)
+		fmt.Fprintf(w, "%sLogo:        %s\n", prefix, e.Logo)
+		fmt.Fprintf(w, "%sCountry:     %s\n", prefix, e.Country)
+		fmt.Fprintf(w, "%sDiscord:     %s\n", prefix, e.Discord)
+		fmt.Fprintf(w, "%sTelegram:    %s\n", prefix, e.Telegram)
+		fmt.Fprintf(w, "%sGitHub:      %s\n", prefix, e.GitHub)
+		fmt.Fprintf(w, "%sCommission:  %s\n", prefix, e.CommissionPolicy)
+	}
 }
 
 // PrettyType returns a representation of EntityMetadata that can be used for
@@ -239,15 +417,7 @@ func (s *SignedEntityMetadata) Open(meta *EntityMetadata) error {
 
 // Save serializes and writes entity metadata to the given writer.
 func (s *SignedEntityMetadata) Save(w io.Writer) error {
-	b, err := json.Marshal(s)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	if _, err = w.Write(b); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
-	}
-	return nil
+	return saveSigned(w, &s.Signed)
 }
 
 // SignEntityMetadata serializes the EntityMetadata and signs the result.
@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityMetadataValidateFields(t *testing.T) {
+	require := require.New(t)
+
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Name:      "hello world",
+		URL:       "https://helloworld.io",
+		Email:     "hello@world.org",
+		Keybase:   "helloworld",
+		Twitter:   "helloworld",
+	}
+	require.Empty(entity.ValidateFields(), "ValidateFields should not fail on valid metadata")
+	require.NoError(entity.ValidateBasic(), "ValidateBasic should not fail on valid metadata")
+
+	entity = &EntityMetadata{
+		Versioned: cbor.NewVersioned(0),
+		Name:      "this is a name but it is soooooooooooooooooooo long",
+		URL:       "http://helloworld.io",
+		Email:     "Hello World <hello@world.org>",
+		Keybase:   "tootootootootootootootootootoolong",
+		Twitter:   "hello-world",
+	}
+	errs := entity.ValidateFields()
+	require.Len(errs, 6, "ValidateFields should return all field errors")
+
+	require.Equal("v", errs[0].Field)
+	require.Equal(RuleSupportedVersion, errs[0].Rule)
+	require.Equal(0, errs[0].Actual)
+	require.Equal(MinSupportedVersion, errs[0].Limit)
+
+	require.Equal("name", errs[1].Field)
+	require.Equal(RuleMaxLength, errs[1].Rule)
+	require.Equal(len(entity.Name), errs[1].Actual)
+	require.Equal(MaxEntityNameLength, errs[1].Limit)
+
+	require.Equal("url", errs[2].Field)
+	require.Equal(RuleFormat, errs[2].Rule)
+	require.Equal("email", errs[3].Field)
+	require.Equal(RuleFormat, errs[3].Rule)
+	require.Equal("keybase", errs[4].Field)
+	require.Equal(RuleMaxLength, errs[4].Rule)
+	require.Equal("twitter", errs[5].Field)
+	require.Equal(RuleFormat, errs[5].Rule)
+
+	err := entity.ValidateBasic()
+	require.Equal(errs[0], err, "ValidateBasic should return the first field error")
+	require.EqualError(err, "unsupported entity metadata version: 0")
+}
+
+func TestEntityMetadataV2(t *testing.T) {
+	require := require.New(t)
+
+	entity := &EntityMetadata{
+		Versioned:        cbor.NewVersioned(2),
+		Name:             "hello world",
+		Description:      "hello world description",
+		Logo:             "https://helloworld.io/logo.png",
+		Country:          "SI",
+		Discord:          "hello.world",
+		Telegram:         "hello_world",
+		GitHub:           "hello-world",
+		CommissionPolicy: "never above 10%",
+	}
+	require.NoError(entity.ValidateBasic(), "ValidateBasic should not fail on valid v2 metadata")
+
+	var buf bytes.Buffer
+	entity.PrettyPrint(context.Background(), "", &buf)
+	require.Contains(buf.String(), "Country:     SI")
+
+	// Extended fields are not allowed in version 1 statements.
+	entity.Versioned = cbor.NewVersioned(1)
+	errs := entity.ValidateFields()
+	require.Len(errs, 1)
+	require.Equal("v", errs[0].Field)
+	require.Equal(RuleSupportedVersion, errs[0].Rule)
+	require.Equal(ExtendedFieldsVersion, errs[0].Limit)
+	require.True(errors.Is(errs[0], ErrUnsupportedVersion))
+
+	// Version 1 statements without extended fields are unaffected.
+	buf.Reset()
+	entity = &EntityMetadata{Versioned: cbor.NewVersioned(1), Name: "hello world"}
+	require.NoError(entity.ValidateBasic())
+	entity.PrettyPrint(context.Background(), "", &buf)
+	require.NotContains(buf.String(), "Country")
+}
+
+func TestEntityMetadataRevocation(t *testing.T) {
+	require := require.New(t)
+
+	entity := &EntityMetadata{
+		Versioned:        cbor.NewVersioned(2),
+		Serial:           2,
+		Revoked:          true,
+		RevocationReason: "leaving the network",
+	}
+	require.NoError(entity.ValidateBasic(), "ValidateBasic should not fail on a revocation tombstone")
+
+	var buf bytes.Buffer
+	entity.PrettyPrint(context.Background(), "", &buf)
+	require.Contains(buf.String(), "Reason:  leaving the network")
+	require.NotContains(buf.String(), "Name")
+
+	// Revocation is not allowed in version 1 statements.
+	entity.Versioned = cbor.NewVersioned(1)
+	errs := entity.ValidateFields()
+	require.Len(errs, 1)
+	require.Equal("v", errs[0].Field)
+
+	// Revocation tombstones must not contain any metadata.
+	entity.Versioned = cbor.NewVersioned(2)
+	entity.Name = "hello world"
+	entity.Country = "SI"
+	errs = entity.ValidateFields()
+	require.Len(errs, 2)
+	require.Equal("name", errs[0].Field)
+	require.Equal("country", errs[1].Field)
+	require.True(errors.Is(errs[0], ErrMalformedField))
+
+	// Revocation reason requires revoked metadata.
+	entity = &EntityMetadata{Versioned: cbor.NewVersioned(2), RevocationReason: "leaving the network"}
+	errs = entity.ValidateFields()
+	require.Len(errs, 1)
+	require.Equal("revocation_reason", errs[0].Field)
+}
+
+func TestEntityMetadataErrors(t *testing.T) {
+	require := require.New(t)
+
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(0),
+		Name:      "this is a name but it is soooooooooooooooooooo long",
+		URL:       "http://helloworld.io",
+	}
+	errs := entity.ValidateFields()
+	require.Len(errs, 3)
+	require.True(errors.Is(errs[0], ErrUnsupportedVersion))
+	var tooLong *ErrFieldTooLong
+	require.True(errors.As(errs[1], &tooLong))
+	require.Equal("name", tooLong.Field)
+	require.Equal(len(entity.Name), tooLong.Len)
+	require.Equal(MaxEntityNameLength, tooLong.Max)
+	require.True(errors.Is(errs[2], ErrMalformedField))
+	require.True(errors.Is(entity.ValidateBasic(), ErrUnsupportedVersion))
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	load := func(id signature.PublicKey, signed *SignedEntityMetadata) error {
+		var buf bytes.Buffer
+		require.NoError(signed.Save(&buf), "Save")
+		return new(EntityMetadata).Load(id, &buf)
+	}
+
+	// Validation failure.
+	entity.Versioned = cbor.NewVersioned(1)
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	err = load(signer.Public(), signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.As(err, &tooLong))
+	require.Equal("name", tooLong.Field)
+
+	// Signer mismatch.
+	entity.Name = "hello world"
+	entity.URL = ""
+	signed, err = SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(load(signer.Public(), signed), "Load")
+	err = load(other.Public(), signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrSignerMismatch))
+
+	// Bad signature.
+	signed.Signature.Signature[0] ^= 0xff
+	err = load(signer.Public(), signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrBadSignature))
+
+	// Statement too big.
+	err = new(EntityMetadata).Load(signer.Public(), bytes.NewReader(make([]byte, MaxStatementSize+1)))
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrStatementTooBig))
+}
@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"errors"
+	"sort"
+)
+
+// VerifyStatus is the verification status of a single registry statement.
+type VerifyStatus string
+
+const (
+	// VerifyStatusOK is the status of a statement that passed verification.
+	VerifyStatusOK VerifyStatus = "ok"
+	// VerifyStatusBadFilename is the status of a statement with a malformed filename.
+	VerifyStatusBadFilename VerifyStatus = "bad_filename"
+	// VerifyStatusTooBig is the status of a statement exceeding MaxStatementSize.
+	VerifyStatusTooBig VerifyStatus = "too_big"
+	// VerifyStatusMalformed is the status of a statement that cannot be read or decoded.
+	VerifyStatusMalformed VerifyStatus = "malformed"
+	// VerifyStatusSignerMismatch is the status of a statement not signed by the expected signer.
+	VerifyStatusSignerMismatch VerifyStatus = "signer_mismatch"
+	// VerifyStatusBadSignature is the status of a statement with an invalid signature.
+	VerifyStatusBadSignature VerifyStatus = "bad_signature"
+	// VerifyStatusValidationFailure is the status of a statement that fails basic validation.
+	VerifyStatusValidationFailure VerifyStatus = "validation_failure"
+	// VerifyStatusSerialRegression is the status of an updated statement which does not increase
+	// the serial number.
+	VerifyStatusSerialRegression VerifyStatus = "serial_regression"
+	// VerifyStatusRemoved is the status of a statement which has been removed by an update.
+	VerifyStatusRemoved VerifyStatus = "removed"
+	// VerifyStatusRevokedUpdate is the status of a statement which updates a revoked statement.
+	VerifyStatusRevokedUpdate VerifyStatus = "revoked_update"
+	// VerifyStatusUnverifiedOwner is the status of a new runtime statement whose owner cannot be
+	// verified.
+	VerifyStatusUnverifiedOwner VerifyStatus = "unverified_owner"
+)
+
+// VerifyReportEntry is the verification result of a single registry statement.
+type VerifyReportEntry struct {
+	// Kind is the name of the statement kind.
+	Kind string `json:"kind"`
+
+	// Path is the path of the statement relative to the registry base directory.
+	Path string `json:"path"`
+
+	// ID is the identifier of the statement (when the filename is well-formed).
+	ID string `json:"id,omitempty"`
+
+	// Status is the verification status.
+	Status VerifyStatus `json:"status"`
+
+	// Error is the verification error (if any).
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyReport is a report of verifying all statements in the registry.
+type VerifyReport struct {
+	// Entries are the verification results of all checked statements, ordered by path.
+	Entries []*VerifyReportEntry `json:"entries"`
+}
+
+// Failed returns true iff verification of any of the statements failed.
+func (r *VerifyReport) Failed() bool {
+	for _, entry := range r.Entries {
+		if entry.Status != VerifyStatusOK {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *VerifyReport) add(kind *StatementKind, path, id string, err error) {
+	entry := &VerifyReportEntry{
+		Kind:   kind.Name,
+		Path:   path,
+		ID:     id,
+		Status: statementStatus(err),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+func (r *VerifyReport) sort() {
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		return r.Entries[i].Path < r.Entries[j].Path
+	})
+}
+
+// statementError is an error caused by a bad statement. It wraps both the descriptive error and
+// its cause.
+type statementError struct {
+	cause error
+	err   error
+}
+
+func newStatementError(cause, err error) error {
+	return &statementError{
+		cause: cause,
+		err:   err,
+	}
+}
+
+func (e *statementError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statementError) Unwrap() []error {
+	return []error{e.err, e.cause}
+}
+
+// statementStatus returns the verification status corresponding to the given error.
+func statementStatus(err error) VerifyStatus {
+	var fieldErr *FieldError
+	switch {
+	case err == nil:
+		return VerifyStatusOK
+	case errors.Is(err, ErrBadFilename):
+		return VerifyStatusBadFilename
+	case errors.Is(err, ErrStatementTooBig):
+		return VerifyStatusTooBig
+	case errors.Is(err, ErrSignerMismatch):
+		return VerifyStatusSignerMismatch
+	case errors.Is(err, ErrBadSignature):
+		return VerifyStatusBadSignature
+	case errors.As(err, &fieldErr):
+		return VerifyStatusValidationFailure
+	case errors.Is(err, ErrEntityRevoked):
+		return VerifyStatusRevokedUpdate
+	case errors.Is(err, ErrSerialNotIncreased):
+		return VerifyStatusSerialRegression
+	case errors.Is(err, ErrRuntimeOwnerUnverified):
+		return VerifyStatusUnverifiedOwner
+	case isRemovedError(err):
+		return VerifyStatusRemoved
+	default:
+		return VerifyStatusMalformed
+	}
+}
+
+// isRemovedError returns true iff the given error is caused by a statement of any kind having
+// been removed by an update.
+func isRemovedError(err error) bool {
+	for _, kind := range StatementKinds() {
+		if errors.Is(err, kind.ErrRemoved) {
+			return true
+		}
+	}
+	return false
+}
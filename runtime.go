@@ -0,0 +1,262 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+var (
+	// ErrNoSuchRuntime is the error returned where the requested runtime cannot be found.
+	ErrNoSuchRuntime = errors.New("registry: no such runtime")
+
+	// ErrRuntimeRemoved is the error returned where a runtime statement has been removed by an
+	// update.
+	ErrRuntimeRemoved = errors.New("registry: runtime removed")
+
+	// ErrRuntimeOwnerUnverified is the error returned where the entity owning a runtime cannot be
+	// verified (see VerifyOptions).
+	ErrRuntimeOwnerUnverified = errors.New("registry: runtime owner not verified")
+)
+
+const (
+	// MaxRuntimeNameLength is the maximum length of the runtime metadata's Name field.
+	MaxRuntimeNameLength = 50
+	// MaxRuntimeURLLength is the maximum length of the runtime metadata's URL field.
+	MaxRuntimeURLLength = 64
+	// MaxRuntimeDocsURLLength is the maximum length of the runtime metadata's DocsURL field.
+	MaxRuntimeDocsURLLength = 128
+	// MaxRuntimeIconLength is the maximum length of the runtime metadata's Icon field.
+	MaxRuntimeIconLength = 128
+
+	// MinSupportedRuntimeVersion is the minimum supported runtime metadata version.
+	MinSupportedRuntimeVersion = 1
+	// MaxSupportedRuntimeVersion is the maximum supported runtime metadata version.
+	MaxSupportedRuntimeVersion = 1
+)
+
+// RuntimeMetadataSignatureContext is the domain separation context used for runtime metadata.
+var RuntimeMetadataSignatureContext = signature.NewContext("oasis-metadata-registry: runtime")
+
+// RuntimeOwnerLookup looks up the entity owning a runtime, e.g. as given in the runtime descriptor
+// registered on-chain.
+type RuntimeOwnerLookup interface {
+	// RuntimeOwner returns the public key of the entity owning the given runtime.
+	RuntimeOwner(ctx context.Context, id common.Namespace) (signature.PublicKey, error)
+}
+
+// StaticRuntimeOwners is a runtime owner lookup serving a fixed set of runtime owners, e.g. as
+// obtained from the runtime descriptors registered on-chain.
+type StaticRuntimeOwners map[common.Namespace]signature.PublicKey
+
+// RuntimeOwner implements RuntimeOwnerLookup.
+func (o StaticRuntimeOwners) RuntimeOwner(_ context.Context, id common.Namespace) (signature.PublicKey, error) {
+	owner, ok := o[id]
+	if !ok {
+		return signature.PublicKey{}, fmt.Errorf("%w: unknown runtime %s", ErrRuntimeOwnerUnverified, id)
+	}
+	return owner, nil
+}
+
+// RuntimeStatementKind is the runtime metadata statement kind.
+//
+// The first statement for a runtime must be signed by the entity owning the runtime as returned by
+// the RuntimeOwnerLookup given in the VerifyOptions (if any). All subsequent updates must be signed
+// by the same entity.
+var RuntimeStatementKind = &StatementKind{
+	Name:               "runtime",
+	Dir:                registryRuntimeDir,
+	SignatureContext:   RuntimeMetadataSignatureContext,
+	MaxSize:            MaxStatementSize,
+	Optional:           true,
+	ErrNoSuchStatement: ErrNoSuchRuntime,
+	ErrRemoved:         ErrRuntimeRemoved,
+	New: func() Statement {
+		return new(RuntimeMetadata)
+	},
+	ParseFilename: parseNamespaceFilename,
+	Filename:      namespaceFilename,
+	ID: func(_ signature.PublicKey, stmt Statement) StatementID {
+		return stmt.(*RuntimeMetadata).ID
+	},
+	VerifySigner: func(signer signature.PublicKey, stmt Statement) error {
+		return stmt.(*RuntimeMetadata).verifySigner(signer)
+	},
+	VerifyCreate: func(ctx context.Context, opts *VerifyOptions, stmt Statement, _ signature.PublicKey) error {
+		if opts == nil || opts.RuntimeOwners == nil {
+			return nil
+		}
+		return stmt.(*RuntimeMetadata).verifyOwner(ctx, opts.RuntimeOwners)
+	},
+	VerifyUpdate: func(src, dst Statement, _ signature.PublicKey) error {
+		return dst.(*RuntimeMetadata).verifyUpdate(src.(*RuntimeMetadata))
+	},
+}
+
+var _ Statement = (*RuntimeMetadata)(nil)
+
+// RuntimeMetadata contains metadata about a runtime (ParaTime).
+type RuntimeMetadata struct {
+	cbor.Versioned
+
+	// Serial is the serial number of the runtime metadata statement.
+	Serial uint64 `json:"serial"`
+
+	// ID is the runtime identifier.
+	ID common.Namespace `json:"id"`
+
+	// Entity is the public key of the entity owning the runtime. The statement must be signed by
+	// this entity, which must own the runtime when the first statement for the runtime is created
+	// and cannot be changed afterwards.
+	Entity signature.PublicKey `json:"entity"`
+
+	// Name is the runtime's display name.
+	Name string `json:"name,omitempty"`
+
+	// URL is the runtime's website URL.
+	URL string `json:"url,omitempty"`
+
+	// DocsURL is the runtime's documentation URL.
+	DocsURL string `json:"docs_url,omitempty"`
+
+	// Icon is an URL of the runtime's icon.
+	Icon string `json:"icon,omitempty"`
+}
+
+// Equal compares vs another runtime metadata for equality.
+func (r *RuntimeMetadata) Equal(other *RuntimeMetadata) bool {
+	return bytes.Equal(cbor.Marshal(r), cbor.Marshal(other))
+}
+
+// StatementSerial returns the serial number of the runtime metadata statement.
+func (r *RuntimeMetadata) StatementSerial() uint64 {
+	return r.Serial
+}
+
+// ValidateBasic performs basic validity checks on the runtime metadata.
+//
+// In case the metadata is invalid, the first validation error is returned. Use ValidateFields to
+// get all of them.
+func (r *RuntimeMetadata) ValidateBasic() error {
+	if errs := r.ValidateFields(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateFields performs basic validity checks on the runtime metadata and returns all field
+// validation errors.
+func (r *RuntimeMetadata) ValidateFields() ValidationErrors {
+	var v validator
+	v.version("v", "runtime metadata", r.Versioned.V, MinSupportedRuntimeVersion, MaxSupportedRuntimeVersion)
+	if r.Entity == (signature.PublicKey{}) || !r.Entity.IsValid() {
+		v.formatf("entity", "runtime entity public key is missing or invalid")
+	}
+	v.maxLength("name", "runtime name", r.Name, MaxRuntimeNameLength)
+	v.url("url", "runtime URL", r.URL, MaxRuntimeURLLength)
+	v.url("docs_url", "runtime documentation URL", r.DocsURL, MaxRuntimeDocsURLLength)
+	v.url("icon", "runtime icon URL", r.Icon, MaxRuntimeIconLength)
+	return v.errs
+}
+
+// verifySigner checks that the given signer is the runtime entity.
+func (r *RuntimeMetadata) verifySigner(signer signature.PublicKey) error {
+	if !signer.Equal(r.Entity) {
+		return fmt.Errorf("runtime metadata signer does not match runtime entity (expected: %s got: %s)",
+			r.Entity,
+			signer,
+		)
+	}
+	return nil
+}
+
+// verifyOwner checks that the runtime entity is the entity owning the runtime as returned by the
+// given runtime owner lookup.
+func (r *RuntimeMetadata) verifyOwner(ctx context.Context, lookup RuntimeOwnerLookup) error {
+	owner, err := lookup.RuntimeOwner(ctx, r.ID)
+	if err != nil {
+		return newStatementError(ErrRuntimeOwnerUnverified,
+			fmt.Errorf("failed to look up owner of runtime %s: %w", r.ID, err),
+		)
+	}
+	if !owner.Equal(r.Entity) {
+		return newStatementError(ErrSignerMismatch,
+			fmt.Errorf("runtime metadata entity is not the runtime owner (expected: %s got: %s)",
+				owner,
+				r.Entity,
+			),
+		)
+	}
+	return nil
+}
+
+// verifyUpdate checks that the runtime metadata is a valid update of the existing runtime
+// metadata.
+func (r *RuntimeMetadata) verifyUpdate(existing *RuntimeMetadata) error {
+	if !r.Entity.Equal(existing.Entity) {
+		return newStatementError(ErrSignerMismatch,
+			fmt.Errorf("updated runtime metadata must not change the runtime entity (existing: %s provided: %s)",
+				existing.Entity,
+				r.Entity,
+			),
+		)
+	}
+	return nil
+}
+
+// Load loads and verifies runtime metadata from a given reader containing signed runtime metadata.
+func (r *RuntimeMetadata) Load(id common.Namespace, rd io.Reader) error {
+	return RuntimeStatementKind.load(id, rd, r)
+}
+
+// PrettyPrint writes a pretty-printed representation of RuntimeMetadata to the
+// given writer.
+func (r *RuntimeMetadata) PrettyPrint(ctx context.Context, prefix string, w io.Writer) {
+	fmt.Fprintf(w, "%sVersion:  %d\n", prefix, r.V)
+	fmt.Fprintf(w, "%sSerial:   %d\n", prefix, r.Serial)
+	fmt.Fprintf(w, "%sID:       %s\n", prefix, r.ID)
+	fmt.Fprintf(w, "%sEntity:   %s\n", prefix, r.Entity)
+	fmt.Fprintf(w, "%sName:     %s\n", prefix, r.Name)
+	fmt.Fprintf(w, "%sURL:      %s\n", prefix, r.URL)
+	fmt.Fprintf(w, "%sDocs URL: %s\n", prefix, r.DocsURL)
+	fmt.Fprintf(w, "%sIcon:     %s\n", prefix, r.Icon)
+}
+
+// PrettyType returns a representation of RuntimeMetadata that can be used for
+// pretty printing.
+func (r RuntimeMetadata) PrettyType() (interface{}, error) {
+	return r, nil
+}
+
+// SignedRuntimeMetadata is a signed runtime metadata statement.
+type SignedRuntimeMetadata struct {
+	signature.Signed
+}
+
+// Open first verifies the blob signature and then unmarshals the blob.
+func (s *SignedRuntimeMetadata) Open(meta *RuntimeMetadata) error {
+	return s.Signed.Open(RuntimeMetadataSignatureContext, meta)
+}
+
+// Save serializes and writes runtime metadata to the given writer.
+func (s *SignedRuntimeMetadata) Save(w io.Writer) error {
+	return saveSigned(w, &s.Signed)
+}
+
+// SignRuntimeMetadata serializes the RuntimeMetadata and signs the result.
+func SignRuntimeMetadata(signer signature.Signer, meta *RuntimeMetadata) (*SignedRuntimeMetadata, error) {
+	signed, err := signature.SignSigned(signer, RuntimeMetadataSignatureContext, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedRuntimeMetadata{
+		Signed: *signed,
+	}, nil
+}
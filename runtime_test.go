@@ -0,0 +1,231 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRuntimeID(require *require.Assertions) common.Namespace {
+	var id common.Namespace
+	require.NoError(id.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001"))
+	return id
+}
+
+// writeTestRuntime writes the signed runtime metadata directly to the registry, so that registries
+// with invalid updates can be created.
+func writeTestRuntime(require *require.Assertions, fs billy.Filesystem, signer signature.Signer, runtime *RuntimeMetadata) {
+	signed, err := SignRuntimeMetadata(signer, runtime)
+	require.NoError(err, "SignRuntimeMetadata")
+	f, err := fs.Create(RuntimeStatementKind.Path(runtime.ID))
+	require.NoError(err, "Create")
+	defer f.Close()
+	require.NoError(signed.Save(f), "Save")
+}
+
+func TestRuntimeMetadataLoad(t *testing.T) {
+	require := require.New(t)
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	id := newTestRuntimeID(require)
+	load := func(id common.Namespace, signed *SignedRuntimeMetadata) error {
+		var buf bytes.Buffer
+		require.NoError(signed.Save(&buf), "Save")
+		return new(RuntimeMetadata).Load(id, &buf)
+	}
+
+	runtime := &RuntimeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		ID:        id,
+		Entity:    signer.Public(),
+		Name:      "Hello ParaTime",
+		URL:       "https://helloworld.io",
+		DocsURL:   "https://docs.helloworld.io/paratime",
+		Icon:      "https://helloworld.io/icon.png",
+	}
+	signed, err := SignRuntimeMetadata(signer, runtime)
+	require.NoError(err, "SignRuntimeMetadata")
+	require.NoError(load(id, signed), "Load")
+
+	// Statement for a different runtime.
+	var otherID common.Namespace
+	err = load(otherID, signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrSignerMismatch))
+
+	// Statement not signed by the runtime entity.
+	signed, err = SignRuntimeMetadata(other, runtime)
+	require.NoError(err, "SignRuntimeMetadata")
+	err = load(id, signed)
+	require.True(errors.Is(err, ErrCorruptedRegistry))
+	require.True(errors.Is(err, ErrSignerMismatch))
+
+	// Runtime entity is required.
+	runtime.Entity = signature.PublicKey{}
+	errs := runtime.ValidateFields()
+	require.Len(errs, 1)
+	require.Equal("entity", errs[0].Field)
+}
+
+func TestFilesystemProviderRuntimes(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+	require.NoError(fp.Verify(), "Verify should work on an empty registry")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	other := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	id := newTestRuntimeID(require)
+	runtime := &RuntimeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		ID:        id,
+		Entity:    signer.Public(),
+		Name:      "Hello ParaTime",
+	}
+
+	signed, err := SignRuntimeMetadata(other, runtime)
+	require.NoError(err, "SignRuntimeMetadata")
+	err = fp.UpdateRuntime(signed)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateRuntime should fail for foreign signers")
+
+	signed, err = SignRuntimeMetadata(signer, runtime)
+	require.NoError(err, "SignRuntimeMetadata")
+	require.NoError(fp.UpdateRuntime(signed), "UpdateRuntime")
+	err = fp.UpdateRuntime(signed)
+	require.True(errors.Is(err, ErrSerialNotIncreased), "UpdateRuntime should fail if serial number is not bumped")
+
+	fetchedRuntime, err := fp.GetRuntime(ctx, id)
+	require.NoError(err, "GetRuntime")
+	require.EqualValues(runtime, fetchedRuntime, "GetRuntime should return the same runtime")
+
+	_, err = fp.GetRuntime(ctx, common.Namespace{})
+	require.Equal(ErrNoSuchRuntime, err)
+
+	runtimes, err := fp.GetRuntimes(ctx)
+	require.NoError(err, "GetRuntimes")
+	require.Len(runtimes, 1)
+	require.EqualValues(runtime, runtimes[id])
+
+	// Runtime entity cannot be changed by an update.
+	fs2 := memfs.New()
+	fp2, err := NewFilesystemProviderWithConfig(fs2, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp2.Init(), "Init")
+	changed := *runtime
+	changed.Serial++
+	changed.Entity = other.Public()
+	signed, err = SignRuntimeMetadata(other, &changed)
+	require.NoError(err, "SignRuntimeMetadata")
+	err = fp.UpdateRuntime(signed)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateRuntime should fail if the runtime entity changes")
+	writeTestRuntime(require, fs2, other, &changed)
+	err = fp2.VerifyUpdate(fp)
+	require.True(errors.Is(err, ErrSignerMismatch), "VerifyUpdate should fail if the runtime entity changes")
+
+	// Runtime statements cannot be removed by an update.
+	emptyFp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(emptyFp.Init(), "Init")
+	err = emptyFp.VerifyUpdate(fp)
+	require.True(errors.Is(err, ErrRuntimeRemoved))
+
+	report, err := emptyFp.VerifyWithReport(context.Background(), fp)
+	require.NoError(err, "VerifyWithReport")
+	require.Len(report.Entries, 1)
+	require.Equal(VerifyStatusRemoved, report.Entries[0].Status)
+	require.Equal(id.String(), report.Entries[0].ID)
+}
+
+func TestFilesystemProviderRuntimeOwner(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	owner := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	squatter := memorySigner.NewTestSigner("metadata-registry-tools other test entity signer")
+	id := newTestRuntimeID(require)
+	owners := StaticRuntimeOwners{id: owner.Public()}
+
+	newProvider := func(fs billy.Filesystem, owners RuntimeOwnerLookup) MutableStatementProvider {
+		p, err := NewFilesystemProviderWithConfig(fs, FilesystemConfig{RuntimeOwners: owners})
+		require.NoError(err, "NewFilesystemProviderWithConfig")
+		require.NoError(p.Init(), "Init")
+		return p
+	}
+	emptyFp := newProvider(memfs.New(), nil)
+
+	runtime := &RuntimeMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Serial:    1,
+		ID:        id,
+		Entity:    owner.Public(),
+		Name:      "Hello ParaTime",
+	}
+	signed, err := SignRuntimeMetadata(owner, runtime)
+	require.NoError(err, "SignRuntimeMetadata")
+
+	// Without an owner lookup, the runtime owner is not verified.
+	require.NoError(newProvider(memfs.New(), nil).UpdateRuntime(signed), "UpdateRuntime")
+
+	// With an owner lookup, runtimes unknown to the lookup are rejected.
+	err = newProvider(memfs.New(), StaticRuntimeOwners{}).UpdateRuntime(signed)
+	require.True(errors.Is(err, ErrRuntimeOwnerUnverified), "UpdateRuntime should fail for unknown runtimes")
+
+	// Other entities cannot claim the runtime, even with the maximum serial number.
+	fp := newProvider(memfs.New(), owners)
+	squatted := *runtime
+	squatted.Serial = math.MaxUint64
+	squatted.Entity = squatter.Public()
+	squatted.Name = "Squatted ParaTime"
+	squattedSigned, err := SignRuntimeMetadata(squatter, &squatted)
+	require.NoError(err, "SignRuntimeMetadata")
+	err = fp.UpdateRuntime(squattedSigned)
+	require.True(errors.Is(err, ErrSignerMismatch), "UpdateRuntime should fail for other entities")
+
+	squattedFs := memfs.New()
+	squattedFp := newProvider(squattedFs, owners)
+	writeTestRuntime(require, squattedFs, squatter, &squatted)
+	err = squattedFp.VerifyUpdate(emptyFp)
+	require.True(errors.Is(err, ErrSignerMismatch), "VerifyUpdate should fail for other entities")
+	squattedFp, err = NewFilesystemProviderWithConfig(squattedFs, FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(squattedFp.VerifyUpdate(emptyFp), "VerifyUpdate should not verify the owner without a lookup")
+
+	// The runtime owner can create the statement.
+	require.NoError(fp.UpdateRuntime(signed), "UpdateRuntime")
+	require.NoError(fp.VerifyUpdate(emptyFp), "VerifyUpdate")
+	fetched, err := fp.GetRuntime(ctx, id)
+	require.NoError(err, "GetRuntime")
+	require.Equal(owner.Public(), fetched.Entity)
+
+	// Updates of existing runtimes are not verified against the owner lookup.
+	updated := *runtime
+	updated.Serial++
+	updated.Name = "Hello my ParaTime"
+	signed, err = SignRuntimeMetadata(owner, &updated)
+	require.NoError(err, "SignRuntimeMetadata")
+	unknownFs := memfs.New()
+	unknownFp := newProvider(unknownFs, StaticRuntimeOwners{})
+	writeTestRuntime(require, unknownFs, owner, runtime)
+	require.NoError(unknownFp.UpdateRuntime(signed), "UpdateRuntime")
+
+	report, err := unknownFp.VerifyWithReport(context.Background(), emptyFp)
+	require.NoError(err, "VerifyWithReport")
+	require.True(report.Failed(), "VerifyWithReport should fail for unknown runtimes")
+	require.Equal(VerifyStatusUnverifiedOwner, report.Entries[0].Status)
+}
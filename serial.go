@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// ErrSerialOverflow is the error returned where no serial number greater than the serial number of
+// the existing statement exists.
+var ErrSerialOverflow = errors.New("registry: serial number overflow")
+
+// SerialMode is the mode of automatically assigning statement serial numbers.
+type SerialMode string
+
+const (
+	// SerialModeIncrement assigns the serial number of the existing statement plus one, unless a
+	// greater serial number has been provided.
+	SerialModeIncrement SerialMode = "increment"
+	// SerialModeTimestamp assigns the current Unix timestamp (in seconds), unless the serial
+	// number of the existing statement is not lower, in which case it is incremented instead.
+	SerialModeTimestamp SerialMode = "timestamp"
+)
+
+// NextSerial returns the serial number of a statement updating the existing statement with the
+// given serial number (zero if there is none), given the serial number provided in the statement.
+func NextSerial(mode SerialMode, existing, provided uint64, now time.Time) (uint64, error) {
+	var serial uint64
+	switch mode {
+	case SerialModeIncrement:
+		serial = provided
+	case SerialModeTimestamp:
+		if ts := now.Unix(); ts > 0 {
+			serial = uint64(ts)
+		}
+	default:
+		return 0, fmt.Errorf("registry: unsupported serial mode: '%s'", mode)
+	}
+
+	if serial <= existing {
+		if existing == math.MaxUint64 {
+			return 0, ErrSerialOverflow
+		}
+		serial = existing + 1
+	}
+	return serial, nil
+}
+
+// AssignEntitySerial sets the serial number of the entity metadata so that it is a valid update
+// of the entity's existing metadata in the registry (if any).
+func AssignEntitySerial(
+	ctx context.Context,
+	p Provider,
+	id signature.PublicKey,
+	meta *EntityMetadata,
+	mode SerialMode,
+) error {
+	var existing uint64
+	switch current, err := p.GetEntity(ctx, id); {
+	case err == nil:
+		existing = current.Serial
+	case errors.Is(err, ErrNoSuchEntity):
+	default:
+		return err
+	}
+
+	serial, err := NextSerial(mode, existing, meta.Serial, time.Now())
+	if err != nil {
+		return err
+	}
+	meta.Serial = serial
+	return nil
+}
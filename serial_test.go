@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextSerial(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1700000000, 0)
+	for _, tc := range []struct {
+		mode     SerialMode
+		existing uint64
+		provided uint64
+		expected uint64
+	}{
+		{SerialModeIncrement, 0, 0, 1},
+		{SerialModeIncrement, 0, 5, 5},
+		{SerialModeIncrement, 5, 1, 6},
+		{SerialModeIncrement, 5, 5, 6},
+		{SerialModeIncrement, 5, 10, 10},
+		{SerialModeTimestamp, 0, 0, 1700000000},
+		{SerialModeTimestamp, 5, 10, 1700000000},
+		{SerialModeTimestamp, 1700000000, 0, 1700000001},
+		{SerialModeTimestamp, 1800000000, 0, 1800000001},
+	} {
+		serial, err := NextSerial(tc.mode, tc.existing, tc.provided, now)
+		require.NoError(err, "NextSerial")
+		require.Equal(tc.expected, serial, "NextSerial(%s, %d, %d)", tc.mode, tc.existing, tc.provided)
+	}
+
+	_, err := NextSerial("bad", 0, 0, now)
+	require.Error(err, "NextSerial should fail for unsupported modes")
+
+	for _, mode := range []SerialMode{SerialModeIncrement, SerialModeTimestamp} {
+		_, err = NextSerial(mode, math.MaxUint64, 0, now)
+		require.True(errors.Is(err, ErrSerialOverflow), "NextSerial(%s) should fail on overflow", mode)
+	}
+}
+
+func TestAssignEntitySerial(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	entity := &EntityMetadata{
+		Versioned: cbor.NewVersioned(1),
+		Name:      "My entity name",
+	}
+
+	// Without existing metadata.
+	require.NoError(AssignEntitySerial(ctx, fp, signer.Public(), entity, SerialModeIncrement), "AssignEntitySerial")
+	require.EqualValues(1, entity.Serial)
+	signed, err := SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+
+	// With existing metadata.
+	require.NoError(AssignEntitySerial(ctx, fp, signer.Public(), entity, SerialModeIncrement), "AssignEntitySerial")
+	require.EqualValues(2, entity.Serial)
+	signed, err = SignEntityMetadata(signer, entity)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+
+	require.NoError(AssignEntitySerial(ctx, fp, signer.Public(), entity, SerialModeTimestamp), "AssignEntitySerial")
+	require.Greater(entity.Serial, uint64(2))
+
+	// Revoked metadata cannot be updated.
+	revocation := &EntityMetadata{
+		Versioned: cbor.NewVersioned(MaxSupportedVersion),
+		Serial:    3,
+		Revoked:   true,
+	}
+	signed, err = SignEntityMetadata(signer, revocation)
+	require.NoError(err, "SignEntityMetadata")
+	require.NoError(fp.UpdateEntity(signed), "UpdateEntity")
+	err = AssignEntitySerial(ctx, fp, signer.Public(), entity, SerialModeIncrement)
+	require.True(errors.Is(err, ErrEntityRevoked), "AssignEntitySerial should fail for revoked entities")
+}
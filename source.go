@@ -0,0 +1,275 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// StatementSource is an untrusted source of encoded signed statements (e.g. a remote registry).
+type StatementSource interface {
+	// GetRawStatements returns all encoded signed statements of the given kind, keyed by the name
+	// of the file storing the statement in the registry (without the extension).
+	GetRawStatements(ctx context.Context, kind *StatementKind) (map[string][]byte, error)
+
+	// GetRawStatement returns a specific encoded signed statement of the given kind.
+	//
+	// In case the statement cannot be found, kind.ErrNoSuchStatement is returned.
+	GetRawStatement(ctx context.Context, kind *StatementKind, id StatementID) ([]byte, error)
+
+	// Revision returns an identifier of the registry revision being served. An empty string is
+	// returned in case it cannot be determined.
+	Revision() string
+}
+
+// snapshotSource is a statement source which can serve a consistent snapshot of the registry, so
+// that operations spanning multiple requests see the registry as of a single point in time.
+type snapshotSource interface {
+	StatementSource
+
+	// snapshot returns a statement source serving a snapshot of the current registry.
+	snapshot(ctx context.Context) (StatementSource, error)
+}
+
+type sourceProvider struct {
+	src      StatementSource
+	cache    *statementCache
+	revision *cachedRevision
+	workers  int
+}
+
+// load verifies the given encoded signed statement of the given kind, unless an identical
+// statement has already been verified and cached.
+func (p *sourceProvider) load(kind *StatementKind, id StatementID, data []byte) (Statement, *signature.Signed, error) {
+	if p.cache != nil {
+		return p.cache.load(kind, id, data)
+	}
+
+	stmt := kind.New()
+	signed, err := kind.loadSigned(id, bytes.NewReader(data), stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stmt, signed, nil
+}
+
+// snapshotProvider returns a provider serving a consistent snapshot of the registry, sharing the
+// cache (if any). In case the source does not support snapshots, the provider itself is returned.
+func (p *sourceProvider) snapshotProvider(ctx context.Context) (*sourceProvider, error) {
+	ss, ok := p.src.(snapshotSource)
+	if !ok {
+		return p, nil
+	}
+	src, err := ss.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceProvider{
+		src:     src,
+		cache:   p.cache,
+		workers: p.workers,
+	}, nil
+}
+
+// Implements snapshotSource.
+func (p *sourceProvider) snapshot(ctx context.Context) (StatementSource, error) {
+	return p.snapshotProvider(ctx)
+}
+
+// Implements SnapshotProvider.
+//
+// In case the source does not support snapshots (e.g. a filesystem registry), the provider itself
+// is returned.
+func (p *sourceProvider) Snapshot(ctx context.Context) (StatementProvider, error) {
+	return p.snapshotProvider(ctx)
+}
+
+// Implements Provider.
+func (p *sourceProvider) Verify() error {
+	return p.VerifyContext(context.Background())
+}
+
+// Implements Provider.
+func (p *sourceProvider) VerifyUpdate(src Provider) error {
+	return p.VerifyUpdateContext(context.Background(), src)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) VerifyContext(ctx context.Context) error {
+	snapshot, err := p.snapshotProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return verifyStatements(ctx, snapshot)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) VerifyUpdateContext(ctx context.Context, src Provider) error {
+	snapshot, err := p.snapshotProvider(ctx)
+	if err != nil {
+		return fmt.Errorf("destination registry is corrupted: %w", err)
+	}
+	return verifyStatementsUpdates(ctx, snapshot, src, nil)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetStatements(ctx context.Context, kind *StatementKind) (map[StatementID]Statement, error) {
+	signed, err := p.GetSignedStatements(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	return unsignedStatements(signed), nil
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetSignedStatements(
+	ctx context.Context,
+	kind *StatementKind,
+) (map[StatementID]*SignedStatement, error) {
+	raw, err := p.src.GetRawStatements(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Statements are verified in parallel.
+	ids := make([]StatementID, len(names))
+	stmts := make([]*SignedStatement, len(names))
+	err = forEachParallel(ctx, p.workers, len(names), func(i int) error {
+		name := names[i]
+		id, err := kind.ParseFilename(name)
+		if err != nil {
+			return newStatementError(ErrBadFilename,
+				fmt.Errorf("%w: %s: bad statement filename '%s': %s", ErrCorruptedRegistry, kind.Name, name, err),
+			)
+		}
+
+		stmt, signed, err := p.load(kind, id, raw[name])
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrStatementTooBig):
+			return err
+		default:
+			return fmt.Errorf("%w: %s: bad statement '%s': %w", ErrCorruptedRegistry, kind.Name, name, err)
+		}
+
+		ids[i], stmts[i] = id, &SignedStatement{Statement: stmt, Signed: signed}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[StatementID]*SignedStatement, len(names))
+	for i, id := range ids {
+		results[id] = stmts[i]
+	}
+
+	// Statements which are no longer in the registry need not be cached anymore.
+	if p.cache != nil {
+		p.cache.retain(kind, results)
+	}
+	return results, nil
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetStatement(ctx context.Context, kind *StatementKind, id StatementID) (Statement, error) {
+	data, err := p.src.GetRawStatement(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	stmt, _, err := p.load(kind, id, data)
+	return stmt, err
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetSignedStatement(
+	ctx context.Context,
+	kind *StatementKind,
+	id StatementID,
+) (*SignedStatement, error) {
+	data, err := p.src.GetRawStatement(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	stmt, signed, err := p.load(kind, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedStatement{
+		Statement: stmt,
+		Signed:    signed,
+	}, nil
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) Revision() string {
+	return p.revision.get(p.src.Revision)
+}
+
+// Implements StatementSource.
+func (p *sourceProvider) GetRawStatements(ctx context.Context, kind *StatementKind) (map[string][]byte, error) {
+	return p.src.GetRawStatements(ctx, kind)
+}
+
+// Implements StatementSource.
+func (p *sourceProvider) GetRawStatement(ctx context.Context, kind *StatementKind, id StatementID) ([]byte, error) {
+	return p.src.GetRawStatement(ctx, kind, id)
+}
+
+// Implements CachingProvider.
+func (p *sourceProvider) Stats() CacheStats {
+	return p.cache.stats()
+}
+
+// Implements CachingProvider.
+func (p *sourceProvider) Purge() {
+	p.cache.purge()
+	p.revision.purge()
+}
+
+// Implements Provider.
+func (p *sourceProvider) GetEntities(ctx context.Context) (map[signature.PublicKey]*EntityMetadata, error) {
+	return getEntities(ctx, p)
+}
+
+// Implements Provider.
+func (p *sourceProvider) GetEntity(ctx context.Context, id signature.PublicKey) (*EntityMetadata, error) {
+	return getEntity(ctx, p, id)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetNodes(ctx context.Context) (map[signature.PublicKey]*NodeMetadata, error) {
+	return getNodes(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetNode(ctx context.Context, id signature.PublicKey) (*NodeMetadata, error) {
+	return getNode(ctx, p, id)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetRuntimes(ctx context.Context) (map[common.Namespace]*RuntimeMetadata, error) {
+	return getRuntimes(ctx, p)
+}
+
+// Implements StatementProvider.
+func (p *sourceProvider) GetRuntime(ctx context.Context, id common.Namespace) (*RuntimeMetadata, error) {
+	return getRuntime(ctx, p, id)
+}
+
+// NewSourceProvider creates a new registry provider serving statements from the given untrusted
+// source. All statements are verified before they are returned.
+func NewSourceProvider(src StatementSource) StatementProvider {
+	return &sourceProvider{src: src}
+}
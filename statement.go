@@ -0,0 +1,410 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/prettyprint"
+)
+
+// ErrNoSuchStatementKind is the error returned where the requested statement kind is not
+// registered.
+var ErrNoSuchStatementKind = errors.New("registry: no such statement kind")
+
+// StatementID is the identifier of the subject of a registry statement (e.g. the public key of an
+// entity). Identifiers must be comparable as they are used as map keys.
+type StatementID interface {
+	fmt.Stringer
+}
+
+// Statement is a registry statement of any kind.
+type Statement interface {
+	prettyprint.PrettyPrinter
+
+	// StatementSerial returns the serial number of the statement.
+	StatementSerial() uint64
+
+	// ValidateBasic performs basic validity checks on the statement.
+	ValidateBasic() error
+}
+
+// SignedStatement is a verified statement together with the signed statement it has been loaded
+// from.
+type SignedStatement struct {
+	// Statement is the verified statement.
+	Statement Statement
+
+	// Signed is the signed statement as stored in the registry.
+	Signed *signature.Signed
+}
+
+// VerifyOptions are the options of verifying registry statements, which are passed to the
+// validation hooks of all statement kinds.
+type VerifyOptions struct {
+	// RuntimeOwners is the lookup used to verify that new runtime statements are signed by the
+	// entity owning the runtime. When nil, the runtime owner is not verified and new runtime
+	// statements only need to be signed by the entity given in the statement.
+	RuntimeOwners RuntimeOwnerLookup
+}
+
+// StatementKind describes a kind of signed registry statements.
+//
+// Registry providers handle all registered statement kinds uniformly, so new kinds can be added
+// via RegisterStatementKind.
+type StatementKind struct {
+	// Name is the name of the statement kind (e.g. "entity").
+	Name string
+
+	// Dir is the directory containing statements of this kind, relative to the registry directory.
+	Dir string
+
+	// SignatureContext is the domain separation context used for signing statements of this kind.
+	SignatureContext signature.Context
+
+	// MaxSize is the maximum encoded signed statement size in bytes. If zero, MaxStatementSize is
+	// used.
+	MaxSize int64
+
+	// Optional is true iff the statement directory may be missing from the registry, e.g. because
+	// the registry has been created before the statement kind was supported.
+	Optional bool
+
+	// ErrNoSuchStatement is the error returned where the requested statement cannot be found.
+	ErrNoSuchStatement error
+
+	// ErrRemoved is the error returned where a statement has been removed by an update.
+	ErrRemoved error
+
+	// New returns a new empty statement of this kind.
+	New func() Statement
+
+	// ParseFilename decodes the identifier of the statement stored in a file with the given name
+	// (without the extension).
+	ParseFilename func(name string) (StatementID, error)
+
+	// Filename returns the name of the file (without the extension) storing the statement with
+	// the given identifier.
+	Filename func(id StatementID) string
+
+	// ID returns the identifier of the given statement signed by signer.
+	ID func(signer signature.PublicKey, stmt Statement) StatementID
+
+	// VerifySigner is an optional validation hook checking that the statement may be signed by
+	// signer.
+	VerifySigner func(signer signature.PublicKey, stmt Statement) error
+
+	// VerifyCreate is an optional validation hook checking that the statement stmt signed by
+	// signer may be added to the registry in case no statement with the same identifier exists.
+	VerifyCreate func(ctx context.Context, opts *VerifyOptions, stmt Statement, signer signature.PublicKey) error
+
+	// VerifyUpdate is an optional validation hook checking that the statement dst signed by
+	// signer is a valid update of the existing statement src. Updates must always increase the
+	// serial number.
+	VerifyUpdate func(src, dst Statement, signer signature.PublicKey) error
+}
+
+func (k *StatementKind) maxSize() int64 {
+	if k.MaxSize == 0 {
+		return MaxStatementSize
+	}
+	return k.MaxSize
+}
+
+// Path returns the path of the statement with the given identifier, relative to the registry
+// base directory.
+func (k *StatementKind) Path(id StatementID) string {
+	return path.Join(registryDir, k.Dir, k.Filename(id)+statementExt)
+}
+
+// Load loads and verifies a statement of this kind from a given reader containing the signed
+// statement.
+func (k *StatementKind) Load(id StatementID, r io.Reader) (Statement, error) {
+	stmt := k.New()
+	return stmt, k.load(id, r, stmt)
+}
+
+func (k *StatementKind) load(id StatementID, r io.Reader, stmt Statement) error {
+	_, err := k.loadSigned(id, r, stmt)
+	return err
+}
+
+// loadSigned loads and verifies a statement of this kind into stmt and returns the signed
+// statement.
+func (k *StatementKind) loadSigned(id StatementID, r io.Reader, stmt Statement) (*signature.Signed, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, newStatementError(ErrMalformedStatement,
+			fmt.Errorf("%w: failed to read metadata: %s", ErrCorruptedRegistry, err),
+		)
+	}
+	if maxSize := k.maxSize(); int64(len(b)) > maxSize {
+		return nil, newStatementError(ErrStatementTooBig,
+			fmt.Errorf("%w: statement too big (size: %d max: %d)", ErrCorruptedRegistry, len(b), maxSize),
+		)
+	}
+
+	var signed signature.Signed
+	if err = json.Unmarshal(b, &signed); err != nil {
+		return nil, newStatementError(ErrMalformedStatement,
+			fmt.Errorf("%w: failed to unmarshal signed %s metadata: %s", ErrCorruptedRegistry, k.Name, err),
+		)
+	}
+
+	// The statement identifier can only be checked once the statement is opened, as it may be
+	// declared in the statement itself.
+	if err = signed.Open(k.SignatureContext, stmt); err != nil {
+		return nil, newStatementError(ErrBadSignature,
+			fmt.Errorf("%w: failed to verify signed %s metadata: %s", ErrCorruptedRegistry, k.Name, err),
+		)
+	}
+	if stmtID := k.ID(signed.Signature.PublicKey, stmt); stmtID != id {
+		return nil, newStatementError(ErrSignerMismatch,
+			fmt.Errorf("%w: %s metadata does not match expected %s (expected: %s got: %s)",
+				ErrCorruptedRegistry,
+				k.Name,
+				k.Name,
+				id,
+				stmtID,
+			),
+		)
+	}
+	if k.VerifySigner != nil {
+		if err = k.VerifySigner(signed.Signature.PublicKey, stmt); err != nil {
+			return nil, newStatementError(ErrSignerMismatch, fmt.Errorf("%w: %s", ErrCorruptedRegistry, err))
+		}
+	}
+	if err = stmt.ValidateBasic(); err != nil {
+		return nil, newStatementError(err,
+			fmt.Errorf("%w: failed to validate %s metadata: %s", ErrCorruptedRegistry, k.Name, err),
+		)
+	}
+	return &signed, nil
+}
+
+// Open verifies the signed statement of this kind and returns the statement together with its
+// identifier.
+func (k *StatementKind) Open(signed *signature.Signed) (StatementID, Statement, error) {
+	stmt := k.New()
+	if err := signed.Open(k.SignatureContext, stmt); err != nil {
+		return nil, nil, newStatementError(ErrBadSignature, fmt.Errorf("bad signed %s metadata: %w", k.Name, err))
+	}
+	if k.VerifySigner != nil {
+		if err := k.VerifySigner(signed.Signature.PublicKey, stmt); err != nil {
+			return nil, nil, newStatementError(ErrSignerMismatch,
+				fmt.Errorf("bad signed %s metadata: %w", k.Name, err),
+			)
+		}
+	}
+	if err := stmt.ValidateBasic(); err != nil {
+		return nil, nil, fmt.Errorf("bad signed %s metadata: %w", k.Name, err)
+	}
+	return k.ID(signed.Signature.PublicKey, stmt), stmt, nil
+}
+
+// Sign serializes the statement of this kind and signs the result.
+func (k *StatementKind) Sign(signer signature.Signer, stmt Statement) (*signature.Signed, error) {
+	return signature.SignSigned(signer, k.SignatureContext, stmt)
+}
+
+// verifyCreate verifies that the new statement stmt signed by signer may be added to the registry.
+func (k *StatementKind) verifyCreate(
+	ctx context.Context,
+	opts *VerifyOptions,
+	stmt Statement,
+	signer signature.PublicKey,
+) error {
+	if k.VerifyCreate == nil {
+		return nil
+	}
+	return k.VerifyCreate(ctx, opts, stmt, signer)
+}
+
+// verifyUpdate verifies that the statement dst signed by signer is a valid update of the existing
+// statement src.
+func (k *StatementKind) verifyUpdate(src, dst Statement, signer signature.PublicKey) error {
+	if k.VerifyUpdate != nil {
+		if err := k.VerifyUpdate(src, dst, signer); err != nil {
+			return err
+		}
+	}
+	if dst.StatementSerial() <= src.StatementSerial() {
+		return newStatementError(ErrSerialNotIncreased,
+			fmt.Errorf("updated %s metadata must increase serial number (existing: %d provided: %d)",
+				k.Name,
+				src.StatementSerial(),
+				dst.StatementSerial(),
+			),
+		)
+	}
+	return nil
+}
+
+func (k *StatementKind) validate() error {
+	switch {
+	case k.Name == "":
+		return fmt.Errorf("registry: statement kind has no name")
+	case k.Dir == "" || path.Base(k.Dir) != k.Dir || k.Dir == "." || k.Dir == "..":
+		return fmt.Errorf("registry: statement kind '%s' has a bad directory: '%s'", k.Name, k.Dir)
+	case k.SignatureContext == "":
+		return fmt.Errorf("registry: statement kind '%s' has no signature context", k.Name)
+	case k.MaxSize < 0:
+		return fmt.Errorf("registry: statement kind '%s' has a negative maximum size", k.Name)
+	case k.ErrNoSuchStatement == nil || k.ErrRemoved == nil:
+		return fmt.Errorf("registry: statement kind '%s' is missing errors", k.Name)
+	case k.New == nil || k.ParseFilename == nil || k.Filename == nil || k.ID == nil:
+		return fmt.Errorf("registry: statement kind '%s' is missing required functions", k.Name)
+	}
+	return nil
+}
+
+// statementsEqual compares two statements for equality.
+func statementsEqual(a, b Statement) bool {
+	return bytes.Equal(cbor.Marshal(a), cbor.Marshal(b))
+}
+
+// saveSigned serializes and writes a signed statement to the given writer.
+func saveSigned(w io.Writer, signed *signature.Signed) error {
+	b, err := json.Marshal(signed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if _, err = w.Write(b); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// typedStatements converts statements of a single kind to a map of their concrete types.
+func typedStatements[K comparable, S Statement](stmts map[StatementID]Statement) map[K]S {
+	results := make(map[K]S, len(stmts))
+	for id, stmt := range stmts {
+		results[id.(K)] = stmt.(S)
+	}
+	return results
+}
+
+// ParsePublicKey parses a hex or Base64-encoded public key, as accepted by the CLI and the API.
+func ParsePublicKey(raw string) (signature.PublicKey, error) {
+	var pk signature.PublicKey
+	if err := pk.UnmarshalHex(raw); err == nil {
+		return pk, nil
+	}
+	if err := pk.UnmarshalText([]byte(raw)); err != nil {
+		return pk, fmt.Errorf("malformed public key '%s'", raw)
+	}
+	return pk, nil
+}
+
+// parsePublicKeyFilename decodes a public key statement identifier from a filename.
+func parsePublicKeyFilename(name string) (StatementID, error) {
+	var id signature.PublicKey
+	if err := id.UnmarshalHex(name); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// publicKeyFilename returns the filename of a public key statement identifier.
+func publicKeyFilename(id StatementID) string {
+	return publicKeyToFilename(id.(signature.PublicKey))
+}
+
+// parseNamespaceFilename decodes a namespace statement identifier from a filename.
+func parseNamespaceFilename(name string) (StatementID, error) {
+	var id common.Namespace
+	if err := id.UnmarshalHex(name); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// namespaceFilename returns the filename of a namespace statement identifier.
+func namespaceFilename(id StatementID) string {
+	return id.(common.Namespace).Hex()
+}
+
+var statementKinds = struct {
+	sync.RWMutex
+
+	kinds []*StatementKind
+}{
+	kinds: []*StatementKind{
+		EntityStatementKind,
+		NodeStatementKind,
+		RuntimeStatementKind,
+	},
+}
+
+// RegisterStatementKind registers a new statement kind.
+//
+// Statement kinds should be registered before any registry providers are used, e.g. from an init
+// function.
+func RegisterStatementKind(kind *StatementKind) error {
+	if err := kind.validate(); err != nil {
+		return err
+	}
+
+	statementKinds.Lock()
+	defer statementKinds.Unlock()
+
+	for _, existing := range statementKinds.kinds {
+		if existing.Name == kind.Name || existing.Dir == kind.Dir {
+			return fmt.Errorf("registry: statement kind '%s' conflicts with existing kind '%s'",
+				kind.Name,
+				existing.Name,
+			)
+		}
+	}
+	statementKinds.kinds = append(statementKinds.kinds, kind)
+	return nil
+}
+
+// StatementKinds returns all registered statement kinds in registration order.
+func StatementKinds() []*StatementKind {
+	statementKinds.RLock()
+	defer statementKinds.RUnlock()
+
+	return append([]*StatementKind{}, statementKinds.kinds...)
+}
+
+// ParseStatementPath returns the statement kind and the identifier of the statement stored at the
+// given path, relative to the registry base directory (see StatementKind.Path).
+func ParseStatementPath(p string) (*StatementKind, StatementID, error) {
+	dir, name := path.Split(path.Clean(p))
+	if path.Dir(path.Clean(dir)) != registryDir || path.Ext(name) != statementExt {
+		return nil, nil, fmt.Errorf("%w: %s", ErrBadFilename, p)
+	}
+	for _, kind := range StatementKinds() {
+		if kind.Dir != path.Base(dir) {
+			continue
+		}
+
+		id, err := kind.ParseFilename(strings.TrimSuffix(name, statementExt))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %s", ErrBadFilename, p, err)
+		}
+		return kind, id, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrNoSuchStatementKind, p)
+}
+
+// StatementKindByName returns the registered statement kind with the given name.
+func StatementKindByName(name string) (*StatementKind, error) {
+	for _, kind := range StatementKinds() {
+		if kind.Name == name {
+			return kind, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoSuchStatementKind, name)
+}
@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errNoSuchTestStatement        = errors.New("registry: no such test statement")
+	errTestStatementRemoved       = errors.New("registry: test statement removed")
+	testStatementSignatureContext = signature.NewContext("oasis-metadata-registry: test statement")
+)
+
+// testStatement is a statement of a kind registered by the tests.
+type testStatement struct {
+	Serial uint64 `json:"serial"`
+	Note   string `json:"note"`
+}
+
+func (s *testStatement) StatementSerial() uint64 {
+	return s.Serial
+}
+
+func (s *testStatement) ValidateBasic() error {
+	if len(s.Note) > 16 {
+		return fmt.Errorf("test statement note too long")
+	}
+	return nil
+}
+
+func (s *testStatement) PrettyPrint(ctx context.Context, prefix string, w io.Writer) {
+	fmt.Fprintf(w, "%sNote: %s\n", prefix, s.Note)
+}
+
+func (s testStatement) PrettyType() (interface{}, error) {
+	return s, nil
+}
+
+var testStatementKind = &StatementKind{
+	Name:               "test",
+	Dir:                "test",
+	SignatureContext:   testStatementSignatureContext,
+	MaxSize:            512,
+	Optional:           true,
+	ErrNoSuchStatement: errNoSuchTestStatement,
+	ErrRemoved:         errTestStatementRemoved,
+	New: func() Statement {
+		return new(testStatement)
+	},
+	ParseFilename: parsePublicKeyFilename,
+	Filename:      publicKeyFilename,
+	ID: func(signer signature.PublicKey, _ Statement) StatementID {
+		return signer
+	},
+}
+
+func registerTestStatementKind(require *require.Assertions) {
+	if _, err := StatementKindByName(testStatementKind.Name); err == nil {
+		return
+	}
+	require.NoError(RegisterStatementKind(testStatementKind), "RegisterStatementKind")
+}
+
+func TestRegisterStatementKind(t *testing.T) {
+	require := require.New(t)
+
+	registerTestStatementKind(require)
+	require.Error(RegisterStatementKind(testStatementKind), "duplicate kinds should be rejected")
+	require.Error(RegisterStatementKind(&StatementKind{Name: "bad"}), "incomplete kinds should be rejected")
+
+	badDir := *testStatementKind
+	badDir.Name = "bad"
+	badDir.Dir = "../bad"
+	require.Error(RegisterStatementKind(&badDir), "kinds with bad directories should be rejected")
+
+	kind, err := StatementKindByName("runtime")
+	require.NoError(err, "StatementKindByName")
+	require.Equal(RuntimeStatementKind, kind)
+	_, err = StatementKindByName("missing")
+	require.True(errors.Is(err, ErrNoSuchStatementKind))
+
+	kinds := StatementKinds()
+	require.Equal(EntityStatementKind, kinds[0])
+	require.Equal(testStatementKind, kinds[len(kinds)-1])
+}
+
+func TestFilesystemProviderStatements(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	registerTestStatementKind(require)
+
+	fp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(fp.Init(), "Init")
+
+	signer := memorySigner.NewTestSigner("metadata-registry-tools test entity signer")
+	stmt := &testStatement{Serial: 1, Note: "hello world"}
+	signed, err := testStatementKind.Sign(signer, stmt)
+	require.NoError(err, "Sign")
+	require.NoError(fp.UpdateStatement(context.Background(), testStatementKind, signed), "UpdateStatement")
+	err = fp.UpdateStatement(context.Background(), testStatementKind, signed)
+	require.True(errors.Is(err, ErrSerialNotIncreased), "UpdateStatement should fail if serial number is not bumped")
+
+	// The statement is signed using the kind's signature context.
+	_, _, err = EntityStatementKind.Open(signed)
+	require.True(errors.Is(err, ErrBadSignature))
+
+	fetched, err := fp.GetStatement(ctx, testStatementKind, signer.Public())
+	require.NoError(err, "GetStatement")
+	require.EqualValues(stmt, fetched)
+	_, err = fp.GetStatement(ctx, testStatementKind, signature.PublicKey{})
+	require.Equal(errNoSuchTestStatement, err)
+
+	stmts, err := fp.GetStatements(ctx, testStatementKind)
+	require.NoError(err, "GetStatements")
+	require.Len(stmts, 1)
+	require.EqualValues(stmt, stmts[signer.Public()])
+
+	// Statements of other kinds are not affected.
+	entities, err := fp.GetEntities(ctx)
+	require.NoError(err, "GetEntities")
+	require.Empty(entities)
+
+	// Invalid statements are rejected.
+	signed, err = testStatementKind.Sign(signer, &testStatement{Serial: 2, Note: "this note is way too long"})
+	require.NoError(err, "Sign")
+	require.Error(fp.UpdateStatement(context.Background(), testStatementKind, signed), "UpdateStatement should fail for invalid statements")
+
+	// Statements of registered kinds are covered by verification.
+	require.NoError(fp.Verify(), "Verify")
+	emptyFp, err := NewFilesystemProviderWithConfig(memfs.New(), FilesystemConfig{})
+	require.NoError(err, "NewFilesystemProviderWithConfig")
+	require.NoError(emptyFp.Init(), "Init")
+	err = emptyFp.VerifyUpdate(fp)
+	require.True(errors.Is(err, errTestStatementRemoved))
+
+	report, err := emptyFp.VerifyWithReport(context.Background(), fp)
+	require.NoError(err, "VerifyWithReport")
+	require.Len(report.Entries, 1)
+	require.Equal(testStatementKind.Name, report.Entries[0].Kind)
+	require.Equal(VerifyStatusRemoved, report.Entries[0].Status)
+}
@@ -0,0 +1,165 @@
+package testcases
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+
+	registry "github.com/oasisprotocol/metadata-registry-tools"
+)
+
+const (
+	RuntimeValidName      = "this is a runtime name"
+	RuntimeTooLongName    = "this is a runtime name but it is soooooooooooooo long"
+	RuntimeValidURL       = "https://hello.world/paratime"
+	RuntimeTooLongURL     = "https://too.too.too.too.too.too.too.too.too.too.too.too.too.too.long"
+	RuntimeValidDocsURL   = "https://docs.hello.world/paratime/overview"
+	RuntimeValidIcon      = "https://hello.world/paratime/icon.png"
+	runtimeTestIDHex      = "8000000000000000000000000000000000000000000000000000000000000000"
+	runtimeTestEntitySeed = "oasis-metadata-registry test cases: runtime entity"
+)
+
+// RuntimeMetadataTestCase is a runtime metadata test case.
+type RuntimeMetadataTestCase struct {
+	Name        string
+	RuntimeMeta registry.RuntimeMetadata
+	Valid       bool
+}
+
+var (
+	RuntimeTooLongDocsURL = "https://docs." + strings.Repeat("too.", 30) + "long/paratime"
+	RuntimeTooLongIcon    = "https://" + strings.Repeat("too.", 30) + "long/icon.png"
+
+	// RuntimeTestID is the runtime identifier used in all runtime metadata test cases.
+	RuntimeTestID = mustNamespace(runtimeTestIDHex)
+	// RuntimeTestEntity is the runtime entity used in all runtime metadata test cases.
+	RuntimeTestEntity = memorySigner.NewTestSigner(runtimeTestEntitySeed).Public()
+
+	// RuntimeMetadataBasicVersionAndSize are the runtime metadata test cases that
+	// contain test cases for basic version and field sizes checks.
+	// NOTE: The ID and Entity fields of all these test cases are set to
+	// RuntimeTestID and RuntimeTestEntity.
+	RuntimeMetadataBasicVersionAndSize []RuntimeMetadataTestCase = []RuntimeMetadataTestCase{
+		{"InvalidVersion0", registry.RuntimeMetadata{Versioned: v0}, false},
+		{"ValidVersion1", registry.RuntimeMetadata{Versioned: v1}, true},
+		{"InvalidVersion2", registry.RuntimeMetadata{Versioned: v2}, false},
+		{"ValidName", registry.RuntimeMetadata{Versioned: v1, Name: RuntimeValidName}, true},
+		{"TooLongName", registry.RuntimeMetadata{Versioned: v1, Name: RuntimeTooLongName}, false},
+		{"ValidURL", registry.RuntimeMetadata{Versioned: v1, URL: RuntimeValidURL}, true},
+		{"TooLongURL", registry.RuntimeMetadata{Versioned: v1, URL: RuntimeTooLongURL}, false},
+		{"ValidDocsURL", registry.RuntimeMetadata{Versioned: v1, DocsURL: RuntimeValidDocsURL}, true},
+		{"TooLongDocsURL", registry.RuntimeMetadata{Versioned: v1, DocsURL: RuntimeTooLongDocsURL}, false},
+		{"ValidIcon", registry.RuntimeMetadata{Versioned: v1, Icon: RuntimeValidIcon}, true},
+		{"TooLongIcon", registry.RuntimeMetadata{Versioned: v1, Icon: RuntimeTooLongIcon}, false},
+	}
+
+	// RuntimeMetadataExtendedVersionAndSize are the runtime metadata test cases
+	// that contain test cases for extended version and field sizes checks.
+	// NOTE: All these test cases contain full runtime metadata structs (i.e. no
+	// fields are empty).
+	RuntimeMetadataExtendedVersionAndSize []RuntimeMetadataTestCase
+
+	// RuntimeMetadataFieldSemantics are the runtime metadata test cases that
+	// contain test cases for checking fields' semantics.
+	// NOTE: The ID and Entity fields of all these test cases are set to
+	// RuntimeTestID and RuntimeTestEntity.
+	RuntimeMetadataFieldSemantics []RuntimeMetadataTestCase = []RuntimeMetadataTestCase{
+		{"ValidURL", registry.RuntimeMetadata{Versioned: v1, URL: RuntimeValidURL}, true},
+		{"BadSchemeURL", registry.RuntimeMetadata{Versioned: v1, URL: "http://hello.world/paratime"}, false},
+		{"BadQueryURL", registry.RuntimeMetadata{Versioned: v1, URL: "https://hello.world/paratime?goo=1"}, false},
+		{"BadURL", registry.RuntimeMetadata{Versioned: v1, URL: "hello.world"}, false},
+		{"ValidDocsURL", registry.RuntimeMetadata{Versioned: v1, DocsURL: RuntimeValidDocsURL}, true},
+		{"BadSchemeDocsURL", registry.RuntimeMetadata{Versioned: v1, DocsURL: "http://docs.hello.world/a"}, false},
+		{"BadFragmentDocsURL", registry.RuntimeMetadata{Versioned: v1, DocsURL: "https://docs.hello.world/a#b"}, false},
+		{"BadPortDocsURL", registry.RuntimeMetadata{Versioned: v1, DocsURL: "https://docs.hello.world:123/a"}, false},
+		{"ValidIcon", registry.RuntimeMetadata{Versioned: v1, Icon: RuntimeValidIcon}, true},
+		{"BadSchemeIcon", registry.RuntimeMetadata{Versioned: v1, Icon: "ftp://hello.world/icon.png"}, false},
+		{"BadIcon", registry.RuntimeMetadata{Versioned: v1, Icon: "127.0.0.1:1234"}, false},
+	}
+)
+
+func mustNamespace(raw string) common.Namespace {
+	var ns common.Namespace
+	if err := ns.UnmarshalHex(raw); err != nil {
+		panic(err)
+	}
+	return ns
+}
+
+// newRuntimeMetadata returns new runtime metadata of the given version for the test runtime.
+func newRuntimeMetadata(v uint16) registry.RuntimeMetadata {
+	return registry.RuntimeMetadata{
+		Versioned: cbor.NewVersioned(v),
+		ID:        RuntimeTestID,
+		Entity:    RuntimeTestEntity,
+	}
+}
+
+// validRuntimeBounds returns true iff all the given runtime metadata fields are
+// within each field's valid bounds.
+func validRuntimeBounds(version uint16, name, url, docsURL, icon string) bool {
+	if version < registry.MinSupportedRuntimeVersion || version > registry.MaxSupportedRuntimeVersion ||
+		len(name) > registry.MaxRuntimeNameLength ||
+		len(url) > registry.MaxRuntimeURLLength ||
+		len(docsURL) > registry.MaxRuntimeDocsURLLength ||
+		len(icon) > registry.MaxRuntimeIconLength {
+		return false
+	}
+	return true
+}
+
+func init() { //nolint:gochecknoinits
+	// Set the test runtime's identifier and entity in all hand-written test cases.
+	for _, tcs := range [][]RuntimeMetadataTestCase{
+		RuntimeMetadataBasicVersionAndSize,
+		RuntimeMetadataFieldSemantics,
+	} {
+		for i := range tcs {
+			tcs[i].RuntimeMeta.ID = RuntimeTestID
+			tcs[i].RuntimeMeta.Entity = RuntimeTestEntity
+		}
+	}
+
+	// Generate test cases for runtime metadata by permutating through all field
+	// value lists below.
+	versions := []uint16{0, 1, 2}
+	serials := []uint64{0, 1, 42, math.MaxUint64}
+	names := []string{RuntimeValidName, RuntimeTooLongName}
+	urls := []string{RuntimeValidURL, RuntimeTooLongURL}
+	docsURLs := []string{RuntimeValidDocsURL, RuntimeTooLongDocsURL}
+	icons := []string{RuntimeValidIcon, RuntimeTooLongIcon}
+
+	count := 0
+	RuntimeMetadataExtendedVersionAndSize = []RuntimeMetadataTestCase{}
+	for _, v := range versions {
+		for _, s := range serials {
+			for _, name := range names {
+				for _, url := range urls {
+					for _, docsURL := range docsURLs {
+						for _, icon := range icons {
+							meta := newRuntimeMetadata(v)
+							meta.Serial = s
+							meta.Name = name
+							meta.URL = url
+							meta.DocsURL = docsURL
+							meta.Icon = icon
+							tc := RuntimeMetadataTestCase{
+								Name:        "ExtendedVersionAndSizeChecks: " + strconv.Itoa(count),
+								RuntimeMeta: meta,
+								Valid:       validRuntimeBounds(v, name, url, docsURL, icon),
+							}
+							RuntimeMetadataExtendedVersionAndSize = append(
+								RuntimeMetadataExtendedVersionAndSize, tc,
+							)
+							count++
+						}
+					}
+				}
+			}
+		}
+	}
+}
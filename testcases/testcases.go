@@ -3,6 +3,7 @@ package testcases
 import (
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 
@@ -20,6 +21,23 @@ const (
 	EntityTooLongKeybase = "tootootootootootootootootootoolong"
 	EntityValidTwitter   = "Hello_world42"
 	EntityTooLongTwitter = "tootootootootootootootootootoolong"
+
+	EntityValidDescription      = "this is a description"
+	EntityValidLogo             = "https://hello.world/logo.png"
+	EntityValidCountry          = "SI"
+	EntityValidDiscord          = "hello_world.42"
+	EntityTooLongDiscord        = "tootootootootootootootootootoolong"
+	EntityValidTelegram         = "Hello_world42"
+	EntityTooLongTelegram       = "tootootootootootootootootootoolong"
+	EntityValidGitHub           = "Hello-world42"
+	EntityTooLongGitHub         = "too-too-too-too-too-too-too-too-too-long"
+	EntityValidCommissionPolicy = "5% commission, never raised by more than 1% per epoch"
+)
+
+var (
+	EntityTooLongLogo             = "https://" + strings.Repeat("too.", 30) + "long/logo.png"
+	EntityTooLongDescription      = strings.Repeat("too long description ", 13)
+	EntityTooLongCommissionPolicy = strings.Repeat("too long policy ", 17)
 )
 
 // EntityMetadataTestCase is an entity metadata test case.
@@ -33,12 +51,14 @@ var (
 	v0 = cbor.NewVersioned(0)
 	v1 = cbor.NewVersioned(1)
 	v2 = cbor.NewVersioned(2)
+	v3 = cbor.NewVersioned(3)
 
 	// EntityMetadataBasicVersionAndSize are the entity metadata test cases that
 	// contain test cases for basic version and field sizes checks.
 	EntityMetadataBasicVersionAndSize []EntityMetadataTestCase = []EntityMetadataTestCase{
 		{"InvalidVersion1", registry.EntityMetadata{Versioned: v0}, false},
-		{"InvalidVersion2", registry.EntityMetadata{Versioned: v2}, false},
+		{"ValidVersion2", registry.EntityMetadata{Versioned: v2}, true},
+		{"InvalidVersion3", registry.EntityMetadata{Versioned: v3}, false},
 		{"ValidName", registry.EntityMetadata{Versioned: v1, Name: EntityValidName}, true},
 		{"TooLongName", registry.EntityMetadata{Versioned: v1, Name: EntityTooLongName}, false},
 		{"ValidURL", registry.EntityMetadata{Versioned: v1, URL: EntityValidURL}, true},
@@ -51,12 +71,57 @@ var (
 		{"TooLongTwitter", registry.EntityMetadata{Versioned: v1, Twitter: EntityTooLongTwitter}, false},
 	}
 
+	// EntityMetadataV2BasicVersionAndSize are the version 2 entity metadata test
+	// cases that contain test cases for basic version and extended field sizes
+	// checks.
+	EntityMetadataV2BasicVersionAndSize []EntityMetadataTestCase = []EntityMetadataTestCase{
+		{"ValidDescription", registry.EntityMetadata{Versioned: v2, Description: EntityValidDescription}, true},
+		{"TooLongDescription", registry.EntityMetadata{Versioned: v2, Description: EntityTooLongDescription}, false},
+		{"V1Description", registry.EntityMetadata{Versioned: v1, Description: EntityValidDescription}, false},
+		{"ValidLogo", registry.EntityMetadata{Versioned: v2, Logo: EntityValidLogo}, true},
+		{"TooLongLogo", registry.EntityMetadata{Versioned: v2, Logo: EntityTooLongLogo}, false},
+		{"V1Logo", registry.EntityMetadata{Versioned: v1, Logo: EntityValidLogo}, false},
+		{"ValidCountry", registry.EntityMetadata{Versioned: v2, Country: EntityValidCountry}, true},
+		{"V1Country", registry.EntityMetadata{Versioned: v1, Country: EntityValidCountry}, false},
+		{"ValidDiscord", registry.EntityMetadata{Versioned: v2, Discord: EntityValidDiscord}, true},
+		{"TooLongDiscord", registry.EntityMetadata{Versioned: v2, Discord: EntityTooLongDiscord}, false},
+		{"V1Discord", registry.EntityMetadata{Versioned: v1, Discord: EntityValidDiscord}, false},
+		{"ValidTelegram", registry.EntityMetadata{Versioned: v2, Telegram: EntityValidTelegram}, true},
+		{"TooLongTelegram", registry.EntityMetadata{Versioned: v2, Telegram: EntityTooLongTelegram}, false},
+		{"V1Telegram", registry.EntityMetadata{Versioned: v1, Telegram: EntityValidTelegram}, false},
+		{"ValidGitHub", registry.EntityMetadata{Versioned: v2, GitHub: EntityValidGitHub}, true},
+		{"TooLongGitHub", registry.EntityMetadata{Versioned: v2, GitHub: EntityTooLongGitHub}, false},
+		{"V1GitHub", registry.EntityMetadata{Versioned: v1, GitHub: EntityValidGitHub}, false},
+		{
+			"ValidCommissionPolicy",
+			registry.EntityMetadata{Versioned: v2, CommissionPolicy: EntityValidCommissionPolicy},
+			true,
+		},
+		{
+			"TooLongCommissionPolicy",
+			registry.EntityMetadata{Versioned: v2, CommissionPolicy: EntityTooLongCommissionPolicy},
+			false,
+		},
+		{
+			"V1CommissionPolicy",
+			registry.EntityMetadata{Versioned: v1, CommissionPolicy: EntityValidCommissionPolicy},
+			false,
+		},
+	}
+
 	// EntityMetadataExtendedVersionAndSize are the entity metadata test cases
 	// that contain test cases for extended version and field sizes checks.
 	// NOTE: All these test cases contain full entity metadata structs (i.e. no
 	// fields are empty).
 	EntityMetadataExtendedVersionAndSize []EntityMetadataTestCase
 
+	// EntityMetadataV2ExtendedVersionAndSize are the version 2 entity metadata
+	// test cases that contain test cases for extended version and extended field
+	// sizes checks.
+	// NOTE: All these test cases contain full entity metadata structs (i.e. no
+	// fields are empty).
+	EntityMetadataV2ExtendedVersionAndSize []EntityMetadataTestCase
+
 	// EntityMetadataFieldSemantics are the entity metadata test cases that
 	// contain test cases for checking fields' semantics.
 	EntityMetadataFieldSemantics []EntityMetadataTestCase = []EntityMetadataTestCase{
@@ -82,6 +147,33 @@ var (
 		{"BadTwitter2", registry.EntityMetadata{Versioned: v1, Twitter: "https://twitter.com/hello"}, false},
 		{"BadTwitter3", registry.EntityMetadata{Versioned: v1, Twitter: "foo-bar"}, false},
 		{"BadTwitter4", registry.EntityMetadata{Versioned: v1, Twitter: "foo:bar"}, false},
+		{"ValidLogo", registry.EntityMetadata{Versioned: v2, Logo: EntityValidLogo}, true},
+		{"BadSchemeLogo", registry.EntityMetadata{Versioned: v2, Logo: "http://hello.world/logo.png"}, false},
+		{"BadQueryLogo", registry.EntityMetadata{Versioned: v2, Logo: "https://hello.world/logo.png?s=1"}, false},
+		{"BadLogo", registry.EntityMetadata{Versioned: v2, Logo: "hello.world/logo.png"}, false},
+		{"ValidCountry", registry.EntityMetadata{Versioned: v2, Country: EntityValidCountry}, true},
+		{"BadCountry1", registry.EntityMetadata{Versioned: v2, Country: "si"}, false},
+		{"BadCountry2", registry.EntityMetadata{Versioned: v2, Country: "SVN"}, false},
+		{"BadCountry3", registry.EntityMetadata{Versioned: v2, Country: "Slovenia"}, false},
+		{"ValidDiscord", registry.EntityMetadata{Versioned: v2, Discord: EntityValidDiscord}, true},
+		{"BadDiscord1", registry.EntityMetadata{Versioned: v2, Discord: "HelloWorld"}, false},
+		{"BadDiscord2", registry.EntityMetadata{Versioned: v2, Discord: "hello#1234"}, false},
+		{"BadDiscord3", registry.EntityMetadata{Versioned: v2, Discord: "https://discord.gg/hello"}, false},
+		{"ValidTelegram", registry.EntityMetadata{Versioned: v2, Telegram: EntityValidTelegram}, true},
+		{"BadTelegram1", registry.EntityMetadata{Versioned: v2, Telegram: "@hello"}, false},
+		{"BadTelegram2", registry.EntityMetadata{Versioned: v2, Telegram: "https://t.me/hello"}, false},
+		{"BadTelegram3", registry.EntityMetadata{Versioned: v2, Telegram: "foo-bar"}, false},
+		{"ValidGitHub", registry.EntityMetadata{Versioned: v2, GitHub: EntityValidGitHub}, true},
+		{"BadGitHub1", registry.EntityMetadata{Versioned: v2, GitHub: "hello-"}, false},
+		{"BadGitHub2", registry.EntityMetadata{Versioned: v2, GitHub: "-hello"}, false},
+		{"BadGitHub3", registry.EntityMetadata{Versioned: v2, GitHub: "foo--bar"}, false},
+		{"BadGitHub4", registry.EntityMetadata{Versioned: v2, GitHub: "foo_bar"}, false},
+		{"BadGitHub5", registry.EntityMetadata{Versioned: v2, GitHub: "https://github.com/hello"}, false},
+		{"ValidRevocation", registry.EntityMetadata{Versioned: v2, Revoked: true}, true},
+		{"ValidRevocationReason", registry.EntityMetadata{Versioned: v2, Revoked: true, RevocationReason: "bye"}, true},
+		{"V1Revocation", registry.EntityMetadata{Versioned: v1, Revoked: true}, false},
+		{"RevocationWithName", registry.EntityMetadata{Versioned: v2, Revoked: true, Name: EntityValidName}, false},
+		{"RevocationReasonNotRevoked", registry.EntityMetadata{Versioned: v2, RevocationReason: "bye"}, false},
 	}
 )
 
@@ -99,10 +191,25 @@ func validBounds(version uint16, name, url, email, keybase, twitter string) bool
 	return true
 }
 
+// validV2Bounds returns true iff all the given version 2 entity metadata fields
+// are within each field's valid bounds.
+func validV2Bounds(version uint16, description, logo, discord, telegram, github, commissionPolicy string) bool {
+	if version < registry.ExtendedFieldsVersion || version > registry.MaxSupportedVersion ||
+		len(description) > registry.MaxEntityDescriptionLength ||
+		len(logo) > registry.MaxEntityLogoLength ||
+		len(discord) > registry.MaxEntityDiscordLength ||
+		len(telegram) > registry.MaxEntityTelegramLength ||
+		len(github) > registry.MaxEntityGitHubLength ||
+		len(commissionPolicy) > registry.MaxEntityCommissionPolicyLength {
+		return false
+	}
+	return true
+}
+
 func init() { //nolint:gochecknoinits
 	// Generate test cases for entity metadata by permutating through all field
 	// value lists below.
-	versions := []uint16{0, 1, 2}
+	versions := []uint16{0, 1, 2, 3}
 	serials := []uint64{0, 1, 10, 42, 1000, 1_000_000, 10_000_000, math.MaxUint64}
 	names := []string{EntityValidName, EntityTooLongName}
 	urls := []string{EntityValidURL, EntityTooLongURL}
@@ -144,4 +251,65 @@ func init() { //nolint:gochecknoinits
 			}
 		}
 	}
+
+	generateV2TestCases()
+}
+
+func generateV2TestCases() {
+	// Generate test cases for version 2 entity metadata by permutating through
+	// all extended field value lists below.
+	versions := []uint16{1, 2, 3}
+	serials := []uint64{1, math.MaxUint64}
+	descriptions := []string{EntityValidDescription, EntityTooLongDescription}
+	logos := []string{EntityValidLogo, EntityTooLongLogo}
+	discordHandles := []string{EntityValidDiscord, EntityTooLongDiscord}
+	telegramHandles := []string{EntityValidTelegram, EntityTooLongTelegram}
+	githubHandles := []string{EntityValidGitHub, EntityTooLongGitHub}
+	commissionPolicies := []string{EntityValidCommissionPolicy, EntityTooLongCommissionPolicy}
+
+	count := 0
+	EntityMetadataV2ExtendedVersionAndSize = []EntityMetadataTestCase{}
+	for _, v := range versions {
+		for _, s := range serials {
+			for _, description := range descriptions {
+				for _, logo := range logos {
+					for _, discord := range discordHandles {
+						for _, telegram := range telegramHandles {
+							for _, github := range githubHandles {
+								for _, commissionPolicy := range commissionPolicies {
+									meta := registry.EntityMetadata{
+										Versioned:        cbor.Versioned{V: v},
+										Serial:           s,
+										Name:             EntityValidName,
+										URL:              EntityValidURL,
+										Email:            EntityValidEmail,
+										Keybase:          EntityValidKeybase,
+										Twitter:          EntityValidTwitter,
+										Description:      description,
+										Logo:             logo,
+										Country:          EntityValidCountry,
+										Discord:          discord,
+										Telegram:         telegram,
+										GitHub:           github,
+										CommissionPolicy: commissionPolicy,
+									}
+									tc := EntityMetadataTestCase{
+										Name:       "V2ExtendedVersionAndSizeChecks: " + strconv.Itoa(count),
+										EntityMeta: meta,
+										Valid: validV2Bounds(
+											v, description, logo, discord, telegram, github, commissionPolicy,
+										),
+									}
+									EntityMetadataV2ExtendedVersionAndSize = append(
+										EntityMetadataV2ExtendedVersionAndSize, tc,
+									)
+									count++
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
 }
@@ -27,7 +27,41 @@ func TestEntityMetadata(t *testing.T) {
 		entityMetadataValidateBasic(require, tc)
 	}
 
+	for _, tc := range EntityMetadataV2BasicVersionAndSize {
+		entityMetadataValidateBasic(require, tc)
+	}
+
+	for _, tc := range EntityMetadataV2ExtendedVersionAndSize {
+		entityMetadataValidateBasic(require, tc)
+	}
+
 	for _, tc := range EntityMetadataFieldSemantics {
 		entityMetadataValidateBasic(require, tc)
 	}
 }
+
+func runtimeMetadataValidateBasic(require *require.Assertions, tc RuntimeMetadataTestCase) {
+	err := tc.RuntimeMeta.ValidateBasic()
+	switch tc.Valid {
+	case true:
+		require.NoError(err, "ValidateBasic should not fail on %s", tc.Name)
+	case false:
+		require.Error(err, "ValidateBasic should fail on %s", tc.Name)
+	}
+}
+
+func TestRuntimeMetadata(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range RuntimeMetadataBasicVersionAndSize {
+		runtimeMetadataValidateBasic(require, tc)
+	}
+
+	for _, tc := range RuntimeMetadataExtendedVersionAndSize {
+		runtimeMetadataValidateBasic(require, tc)
+	}
+
+	for _, tc := range RuntimeMetadataFieldSemantics {
+		runtimeMetadataValidateBasic(require, tc)
+	}
+}
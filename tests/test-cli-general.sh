@@ -14,6 +14,7 @@ cd ${REGISTRY_DIR}/fork-1
 
 # Make sure all test entity private keys have correct permissions.
 find ${FIXTURES_DIR} -name entity.pem -exec chmod 600 {} \;
+find ${FIXTURES_DIR} -name identity.pem -exec chmod 600 {} \;
 
 # Initialize the registry.
 ${OASIS_REGISTRY} init
@@ -39,6 +40,56 @@ ${OASIS_REGISTRY} entity update \
 # Verify registry integrity.
 ${OASIS_REGISTRY} verify
 
+# Show entity metadata in the local registry.
+${OASIS_REGISTRY} entity list --path . | tee list.out
+grep -q 'Name:    Hello world' list.out
+test $(${OASIS_REGISTRY} entity list --path . --format json | jq length) -eq 2
+test $(${OASIS_REGISTRY} entity list --path . --format csv | wc -l) -eq 3
+${OASIS_REGISTRY} entity show --path . d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d | tee show.out
+grep -q 'Entity: 0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0=' show.out
+${OASIS_REGISTRY} entity show --path . --format json 0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0= | tee show.json
+test "$(jq -r .metadata.name show.json)" = "Hello world"
+${OASIS_REGISTRY} entity show --format json \
+	registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json | cmp - show.json
+! ${OASIS_REGISTRY} entity show --path . dJyYRlU1Euti2YKMC1S+BNGL05Yf9RN6nVUgyAFykcQ=X
+! ${OASIS_REGISTRY} entity list --path . --format xml
+rm list.out show.out show.json
+
+# Export the local registry as a static mirror.
+${OASIS_REGISTRY} export --path . --static ../mirror-1
+test $(jq '.statements.entity | length' ../mirror-1/index.json) -eq 2
+for f in ../mirror-1/registry/entity/*.json; do
+	test "$(sha256sum < ${f} | cut -d ' ' -f 1)" = \
+		"$(jq -r --arg id $(basename ${f} .json) '.statements.entity[] | select(.id == $id) | .sha256' \
+		../mirror-1/index.json)"
+done
+for f in $(jq -r '.statements.entity[].path' ../mirror-1/index.json); do
+	test "$(sha256sum < ../mirror-1/${f} | cut -d ' ' -f 1)" = "$(basename ${f} .json)"
+done
+! ${OASIS_REGISTRY} export --path .
+
+# Serve the local registry over the HTTP API.
+${OASIS_REGISTRY} serve --path . --address 127.0.0.1:18080 &
+SERVE_PID=$!
+for i in $(seq 50); do
+	curl --silent --fail http://127.0.0.1:18080/v1/entities > entities.json && break
+	sleep 0.1
+done
+test $(jq '.entities | length' entities.json) -eq 2
+curl --silent --fail http://127.0.0.1:18080/v1/entities/0k4gkzWdwk8B%2FzFjUpjoinzTim6uywTogfreuafdRI0= | \
+	jq -e '.metadata.name == "Hello world"'
+test "$(curl --silent --output /dev/null --write-out '%{http_code}' \
+	--header "If-None-Match: \"$(jq -r .revision entities.json)\"" \
+	http://127.0.0.1:18080/v1/entities)" = "304"
+test "$(curl --silent --fail http://127.0.0.1:18080/index.json | jq -c .statements)" = \
+	"$(jq -c .statements ../mirror-1/index.json)"
+curl --silent --fail \
+	http://127.0.0.1:18080/$(jq -r '.statements.entity[0].path' ../mirror-1/index.json) | \
+	cmp - ../mirror-1/$(jq -r '.statements.entity[0].path' ../mirror-1/index.json)
+kill ${SERVE_PID}
+wait ${SERVE_PID}
+rm entities.json
+
 ###############################################################
 # Create a new fork of the registry and update entity metadata.
 ###############################################################
@@ -70,6 +121,11 @@ rm registry/entity/749c9846553512eb62d9828c0b54be04d18bd3961ff5137a9d5520c801729
 ${OASIS_REGISTRY} verify
 ! ${OASIS_REGISTRY} verify --update ../fork-1
 
+# Verify registry integrity with a machine-readable report.
+${OASIS_REGISTRY} verify --format json
+! ${OASIS_REGISTRY} verify --update ../fork-1 --format json | tee report.json
+grep -q '"status": "removed"' report.json
+
 #########################################################
 # Create a bad fork that does not bump the serial number.
 #########################################################
@@ -93,5 +149,355 @@ ${OASIS_REGISTRY} entity update \
 ${OASIS_REGISTRY} verify
 ! ${OASIS_REGISTRY} verify --update ../fork-1
 
+##################################################
+# Create a new fork of the registry and add a node.
+##################################################
+cd ${REGISTRY_DIR}
+cp -a fork-2 fork-5
+cd fork-5
+
+# New node metadata cannot be signed by the node's entity.
+! ${OASIS_REGISTRY} node update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--signer-role entity \
+	${FIXTURES_DIR}/node-1/metadata.json
+
+# Create new node metadata signed by the node itself.
+${OASIS_REGISTRY} node update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/node-1 \
+	${FIXTURES_DIR}/node-1/metadata.json
+${OASIS_REGISTRY} verify --update ../fork-2
+cp -a ${REGISTRY_DIR}/fork-5 ${REGISTRY_DIR}/fork-5-node
+
+# Update node metadata signed by the node's entity.
+${OASIS_REGISTRY} node update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--signer-role entity \
+	${FIXTURES_DIR}/node-1/update.json
+
+# Node metadata without an entity cannot be signed by the entity.
+! ${OASIS_REGISTRY} node update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--signer-role entity \
+	${FIXTURES_DIR}/node-1/update-bad.json
+
+# Verify registry integrity.
+${OASIS_REGISTRY} verify
+${OASIS_REGISTRY} verify --update ../fork-5-node
+
+# Adding node metadata signed by the node's entity is not a valid update.
+! ${OASIS_REGISTRY} verify --update ../fork-2
+
+# Remove the node statement (update verification should fail).
+cd ${REGISTRY_DIR}
+cp -a fork-5 fork-6
+cd fork-6
+rm registry/node/*.json
+${OASIS_REGISTRY} verify
+! ${OASIS_REGISTRY} verify --update ../fork-5
+
+#####################################################################
+# Create a new fork of the registry and add a runtime via the generic
+# statement command.
+#####################################################################
+cd ${REGISTRY_DIR}
+cp -a fork-5 fork-7
+cd fork-7
+
+${OASIS_REGISTRY} statement kinds
+
+RUNTIME_1_OWNER=8000000000000000000000000000000000000000000000000000000000000001=0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0=
+RUNTIME_1_OTHER_OWNER=8000000000000000000000000000000000000000000000000000000000000001=9391840cee32fd13283e7e383838ad89f9704e3860a178db35a372b8b9c2cf10
+RUNTIME_2_OWNER=8000000000000000000000000000000000000000000000000000000000000002=0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0=
+
+# New runtime metadata is rejected when the runtime owner is configured and
+# differs from the signer or is unknown.
+! ${OASIS_REGISTRY} statement update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--runtime-owner ${RUNTIME_2_OWNER} \
+	runtime ${FIXTURES_DIR}/runtime-1/metadata.json
+! ${OASIS_REGISTRY} statement update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--runtime-owner ${RUNTIME_1_OTHER_OWNER} \
+	runtime ${FIXTURES_DIR}/runtime-1/metadata.json
+
+# Create new runtime metadata signed by the runtime's entity.
+${OASIS_REGISTRY} statement update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--runtime-owner ${RUNTIME_1_OWNER} \
+	runtime ${FIXTURES_DIR}/runtime-1/metadata.json
+
+# Runtime metadata cannot be signed by a different entity.
+! ${OASIS_REGISTRY} statement update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-2 \
+	runtime ${FIXTURES_DIR}/runtime-1/update.json
+
+# Update runtime metadata.
+${OASIS_REGISTRY} statement update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	runtime ${FIXTURES_DIR}/runtime-1/update.json
+
+# Unknown statement kinds are rejected.
+! ${OASIS_REGISTRY} statement update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	unknown ${FIXTURES_DIR}/runtime-1/update.json
+
+# Verify registry integrity. The runtime owner is only verified when
+# configured.
+${OASIS_REGISTRY} verify
+${OASIS_REGISTRY} verify --update ../fork-5
+${OASIS_REGISTRY} verify --update ../fork-5 --runtime-owner ${RUNTIME_1_OWNER}
+! ${OASIS_REGISTRY} verify --update ../fork-5 --runtime-owner ${RUNTIME_1_OTHER_OWNER}
+! ${OASIS_REGISTRY} verify --update ../fork-5 --runtime-owner ${RUNTIME_2_OWNER}
+
+#####################################################################
+# Create a new fork of the registry and revoke an entity's metadata.
+#####################################################################
+cd ${REGISTRY_DIR}
+cp -a fork-2 fork-8
+cd fork-8
+
+${OASIS_REGISTRY} entity revoke \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-2 \
+	--reason "Leaving the network"
+
+# Verify registry integrity.
+${OASIS_REGISTRY} verify
+${OASIS_REGISTRY} verify --update ../fork-2
+
+# Revoked entity metadata cannot be updated or revoked again.
+! ${OASIS_REGISTRY} entity update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-2 \
+	${FIXTURES_DIR}/entity-2/metadata.json
+! ${OASIS_REGISTRY} entity revoke \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-2
+
+# Entities without metadata cannot be revoked.
+cd ${REGISTRY_DIR}
+mkdir fork-9
+cd fork-9
+${OASIS_REGISTRY} init
+! ${OASIS_REGISTRY} entity revoke \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-2
+
+# Sign entity metadata offline.
+cd ${REGISTRY_DIR}
+mkdir fork-10
+cd fork-10
+${OASIS_REGISTRY} init
+
+${OASIS_REGISTRY} entity prepare \
+	--output signing-request.json \
+	${FIXTURES_DIR}/entity-1/metadata.json
+
+# Sign the digest with the entity key converted to PKCS #8, as an air-gapped signer would.
+(
+	printf '302e020100300506032b657004220420' | xxd -r -p
+	sed -n '/BEGIN/,/END/p' ${FIXTURES_DIR}/entity-1/entity.pem | grep -v -- ----- | base64 -d | head -c 32
+) | openssl pkey -inform DER -out offline.pem
+jq -r .digest signing-request.json | xxd -r -p > digest.bin
+openssl pkeyutl -sign -rawin -inkey offline.pem -in digest.bin | base64 -w 0 > signature.txt
+
+# Signatures over other metadata or with the wrong key are rejected.
+! ${OASIS_REGISTRY} entity attach-signature \
+	--public-key dJyYRlU1Euti2YKMC1S+BNGL05Yf9RN6nVUgyAFykcQ= \
+	${FIXTURES_DIR}/entity-1/metadata.json signature.txt
+! ${OASIS_REGISTRY} entity attach-signature \
+	--public-key 0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0= \
+	${FIXTURES_DIR}/entity-1/update.json signature.txt
+
+${OASIS_REGISTRY} entity attach-signature \
+	--public-key 0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0= \
+	${FIXTURES_DIR}/entity-1/metadata.json signature.txt
+
+# Verify registry integrity.
+${OASIS_REGISTRY} verify
+cmp registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json \
+	../fork-1/registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json
+
+# Sign entity metadata without a registry and import it into one.
+cd ${REGISTRY_DIR}
+${OASIS_REGISTRY} entity sign \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--output entity-1.json \
+	${FIXTURES_DIR}/entity-1/metadata.json
+${OASIS_REGISTRY} entity sign \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	${FIXTURES_DIR}/entity-1/update.json > entity-1-update.json
+# Invalid metadata is not signed.
+echo '{"v": 1, "serial": 3, "url": "not a url"}' > entity-1-invalid.json
+! ${OASIS_REGISTRY} entity sign \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--output entity-1-bad.json \
+	entity-1-invalid.json
+test ! -e entity-1-bad.json
+# Invalid signed metadata is not shown.
+${OASIS_REGISTRY} entity sign \
+	--assume_yes \
+	--skip-validation \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	--output entity-1-bad.json \
+	entity-1-invalid.json
+! ${OASIS_REGISTRY} entity show entity-1-bad.json
+rm entity-1-bad.json
+# The signed statement is serialized the same way as in the registry.
+cmp entity-1.json fork-1/registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json
+
+mkdir fork-11
+cd fork-11
+${OASIS_REGISTRY} init
+${OASIS_REGISTRY} entity import ../entity-1.json
+${OASIS_REGISTRY} entity import ../entity-1-update.json
+# Serial numbers must increase.
+! ${OASIS_REGISTRY} entity import ../entity-1.json
+# Tampered statements are rejected.
+sed 's/"signature":"./"signature":"A/' ../entity-1.json > tampered.json
+! ${OASIS_REGISTRY} entity import tampered.json
+
+# Verify registry integrity.
+${OASIS_REGISTRY} verify
+cmp registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json \
+	../fork-2/registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json
+
+# Edit entity metadata interactively.
+cd ${REGISTRY_DIR}
+cp -a fork-1 fork-12
+cd fork-12
+
+# Invalid answers are asked for again, empty answers keep the existing values and the serial number
+# is bumped automatically.
+printf '%s\n' "Hello edited world" "not a url" "" "-" "" "" "" "" "SI" "" "" "" "" | \
+	${OASIS_REGISTRY} entity edit \
+		--assume_yes \
+		--signer.dir ${FIXTURES_DIR}/entity-1 | tee edit.out
+grep -q 'Invalid url' edit.out
+${OASIS_REGISTRY} entity show --path . --format json \
+	d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d > show.json
+test "$(jq -r .metadata.serial show.json)" = "2"
+test "$(jq -r .metadata.v show.json)" = "2"
+test "$(jq -r .metadata.name show.json)" = "Hello edited world"
+test "$(jq -r .metadata.url show.json)" = "https://hello.world"
+test "$(jq -r .metadata.email show.json)" = "null"
+test "$(jq -r .metadata.country show.json)" = "SI"
+
+# Editing is aborted if the input ends early.
+! printf '%s\n' "Hello again" | ${OASIS_REGISTRY} entity edit \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1
+
+# Verify registry integrity.
+${OASIS_REGISTRY} verify
+${OASIS_REGISTRY} verify --update ../fork-1
+rm edit.out show.json
+
+# Assign serial numbers automatically.
+cd ${REGISTRY_DIR}
+cp -a fork-2 fork-13
+cd fork-13
+
+${OASIS_REGISTRY} entity update \
+	--assume_yes \
+	--auto-serial \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	${FIXTURES_DIR}/entity-1/metadata.json
+test "$(${OASIS_REGISTRY} entity show --path . --format json 0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0= | jq .metadata.serial)" = "3"
+${OASIS_REGISTRY} entity update \
+	--assume_yes \
+	--auto-serial=timestamp \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	${FIXTURES_DIR}/entity-1/update.json
+test "$(${OASIS_REGISTRY} entity show --path . --format json 0k4gkzWdwk8B/zFjUpjoinzTim6uywTogfreuafdRI0= | jq .metadata.serial)" -ge 1700000000
+! ${OASIS_REGISTRY} entity update \
+	--assume_yes \
+	--auto-serial=bad \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	${FIXTURES_DIR}/entity-1/update.json
+
+# Verify registry integrity.
+${OASIS_REGISTRY} verify
+${OASIS_REGISTRY} verify --update ../fork-2
+
+###################################################
+# Create a Git-backed registry and inspect history.
+###################################################
+cd ${REGISTRY_DIR}
+mkdir git-1
+cd git-1
+
+GIT="git -c user.name=test -c user.email=test@oasisprotocol.org"
+${GIT} init --quiet --initial-branch master
+
+${OASIS_REGISTRY} init
+${OASIS_REGISTRY} entity update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	${FIXTURES_DIR}/entity-1/metadata.json
+${GIT} add --all
+${GIT} commit --quiet --message "Add entity 1"
+
+${OASIS_REGISTRY} entity update \
+	--assume_yes \
+	--signer.dir ${FIXTURES_DIR}/entity-1 \
+	${FIXTURES_DIR}/entity-1/update.json
+${GIT} add --all
+${GIT} commit --quiet --message "Update entity 1"
+
+# Show entity history.
+${OASIS_REGISTRY} entity history \
+	--git-url file://${REGISTRY_DIR}/git-1 \
+	--git-branch master \
+	d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d | tee history.out
+grep -q 'Name: "Hello world" -> "Hello my world"' history.out
+
+# Show entity history of the local checkout.
+${OASIS_REGISTRY} entity history \
+	--path . \
+	d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d | tee history.out
+grep -q 'Name: "Hello world" -> "Hello my world"' history.out
+
+# Show entity metadata in the Git registry.
+${OASIS_REGISTRY} entity show \
+	--git-url file://${REGISTRY_DIR}/git-1 \
+	--git-branch master \
+	d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d | tee show.out
+grep -q 'Name:    Hello my world' show.out
+${OASIS_REGISTRY} entity list \
+	--git-url file://${REGISTRY_DIR}/git-1 \
+	--git-branch master \
+	--format csv | grep -q 'Hello my world'
+rm show.out
+
+# Verify updates between Git revisions.
+${OASIS_REGISTRY} verify --update-rev HEAD~1
+${OASIS_REGISTRY} verify --range HEAD~1..HEAD
+
+# Remove a statement and restore it in a later commit (range verification should fail).
+cp registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json ../entity-1.json
+${GIT} rm --quiet registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json
+${GIT} commit --quiet --message "Remove entity 1"
+cp ../entity-1.json registry/entity/d24e2093359dc24f01ff31635298e88a7cd38a6eaecb04e881fadeb9a7dd448d.json
+${GIT} add --all
+${GIT} commit --quiet --message "Restore entity 1"
+
+${OASIS_REGISTRY} verify --update-rev HEAD~2
+! ${OASIS_REGISTRY} verify --range HEAD~2..HEAD
+
 # Cleanup if everything went well.
 rm -rf ${REGISTRY_DIR}
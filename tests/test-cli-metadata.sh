@@ -95,5 +95,14 @@ update_entity_and_verify update-email-too-long.json invalid
 update_entity_and_verify update-keybase-too-long.json invalid
 update_entity_and_verify update-twitter-too-long.json invalid
 
+# Update entity metadata with version 2 fields in a version 1 statement.
+update_entity_and_verify update-v1-extended-fields.json invalid
+
+# Update entity metadata with an invalid version 2 field value.
+update_entity_and_verify update-v2-bad-country.json invalid
+
+# Update entity metadata to version 2.
+update_entity_and_verify update-v2.json valid
+
 # Cleanup if everything went well.
 rm -rf ${REGISTRY_DIR}
@@ -58,3 +58,59 @@ func MakeEntityMetadataTestVectorWithSigner(
 		SignerPublicKey:         signer.Public(),
 	}
 }
+
+// RuntimeMetadataTestVector is a runtime metadata test vector.
+type RuntimeMetadataTestVector struct {
+	Kind                     string                         `json:"kind"`
+	SignatureContext         string                         `json:"signature_context"`
+	RuntimeMeta              registry.RuntimeMetadata       `json:"runtime_meta"`
+	SignedRuntimeMeta        registry.SignedRuntimeMetadata `json:"signed_runtime_meta"`
+	EncodedRuntimeMeta       []byte                         `json:"encoded_runtime_meta"`
+	EncodedSignedRuntimeMeta []byte                         `json:"encoded_signed_runtime_meta"`
+	Valid                    bool                           `json:"valid"`
+	SignerPrivateKey         []byte                         `json:"signer_private_key"`
+	SignerPublicKey          signature.PublicKey            `json:"signer_public_key"`
+}
+
+// MakeRuntimeMetadataTestVector generates a new test vector from a runtime metadata.
+//
+// The runtime metadata's Entity field is set to the public key of the signer.
+func MakeRuntimeMetadataTestVector(kind string, meta *registry.RuntimeMetadata, valid bool) RuntimeMetadataTestVector {
+	signer := memorySigner.NewTestSigner(keySeedPrefix + kind)
+	return MakeRuntimeMetadataTestVectorWithSigner(kind, meta, valid, signer)
+}
+
+// MakeRuntimeMetadataTestVectorWithSigner generates a new test vector from a runtime metadata using a specific signer.
+//
+// The runtime metadata's Entity field is set to the public key of the signer.
+func MakeRuntimeMetadataTestVectorWithSigner(
+	kind string,
+	meta *registry.RuntimeMetadata,
+	valid bool,
+	signer signature.Signer,
+) RuntimeMetadataTestVector {
+	runtimeMeta := *meta
+	runtimeMeta.Entity = signer.Public()
+
+	sigMeta, err := registry.SignRuntimeMetadata(signer, &runtimeMeta)
+	if err != nil {
+		panic(err)
+	}
+
+	sigCtx, err := signature.PrepareSignerContext(registry.RuntimeMetadataSignatureContext)
+	if err != nil {
+		panic(err)
+	}
+
+	return RuntimeMetadataTestVector{
+		Kind:                     kind,
+		SignatureContext:         string(sigCtx),
+		RuntimeMeta:              runtimeMeta,
+		SignedRuntimeMeta:        *sigMeta,
+		EncodedRuntimeMeta:       cbor.Marshal(&runtimeMeta),
+		EncodedSignedRuntimeMeta: cbor.Marshal(sigMeta),
+		Valid:                    valid,
+		SignerPrivateKey:         signer.(signature.UnsafeSigner).UnsafeBytes(),
+		SignerPublicKey:          signer.Public(),
+	}
+}
@@ -0,0 +1,249 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// ValidationRule is a validation rule that metadata fields must satisfy.
+type ValidationRule string
+
+const (
+	// RuleSupportedVersion requires the metadata version to be supported.
+	RuleSupportedVersion ValidationRule = "supported_version"
+	// RuleMaxLength requires the field length not to exceed the limit.
+	RuleMaxLength ValidationRule = "max_length"
+	// RuleFormat requires the field to be well-formed.
+	RuleFormat ValidationRule = "format"
+)
+
+var (
+	// ErrUnsupportedVersion is the error returned where the metadata version is not supported.
+	ErrUnsupportedVersion = errors.New("registry: unsupported version")
+
+	// ErrMalformedField is the error returned where a metadata field is malformed.
+	ErrMalformedField = errors.New("registry: malformed field")
+)
+
+// ErrFieldTooLong is the error returned where a metadata field exceeds its maximum length.
+type ErrFieldTooLong struct {
+	// Field is the (JSON) name of the field.
+	Field string
+
+	// Len is the length of the field.
+	Len int
+
+	// Max is the maximum length of the field.
+	Max int
+}
+
+// Error implements error.
+func (e *ErrFieldTooLong) Error() string {
+	return fmt.Sprintf("registry: field '%s' too long (length: %d max: %d)", e.Field, e.Len, e.Max)
+}
+
+// FieldError is a validation error of a single metadata field.
+type FieldError struct {
+	// Field is the (JSON) name of the invalid field.
+	Field string `json:"field"`
+
+	// Rule is the violated validation rule.
+	Rule ValidationRule `json:"rule"`
+
+	// Actual is the actual value of the constrained property (e.g. length) where applicable.
+	Actual int `json:"actual,omitempty"`
+
+	// Limit is the limit of the constrained property (e.g. maximum length) where applicable.
+	Limit int `json:"limit,omitempty"`
+
+	// Message is a human-readable description of the error.
+	Message string `json:"message"`
+
+	// Err is the underlying error (ErrUnsupportedVersion, *ErrFieldTooLong or ErrMalformedField).
+	Err error `json:"-"`
+}
+
+// Error implements error.
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is a list of field validation errors.
+type ValidationErrors []*FieldError
+
+// Error implements error.
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validator collects field validation errors.
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) version(field, desc string, version, min, max uint16) {
+	if version < min || version > max {
+		limit := max
+		if version < min {
+			limit = min
+		}
+		v.errs = append(v.errs, &FieldError{
+			Field:   field,
+			Rule:    RuleSupportedVersion,
+			Actual:  int(version),
+			Limit:   int(limit),
+			Message: fmt.Sprintf("unsupported %s version: %d", desc, version),
+			Err:     ErrUnsupportedVersion,
+		})
+	}
+}
+
+func (v *validator) minVersion(field, desc string, version, min uint16) {
+	v.errs = append(v.errs, &FieldError{
+		Field:   field,
+		Rule:    RuleSupportedVersion,
+		Actual:  int(version),
+		Limit:   int(min),
+		Message: fmt.Sprintf("%s require version %d (version: %d)", desc, min, version),
+		Err:     ErrUnsupportedVersion,
+	})
+}
+
+func (v *validator) maxLength(field, desc, value string, max int) {
+	if len(value) > max {
+		v.errs = append(v.errs, &FieldError{
+			Field:   field,
+			Rule:    RuleMaxLength,
+			Actual:  len(value),
+			Limit:   max,
+			Message: fmt.Sprintf("%s too long (length: %d max: %d)", desc, len(value), max),
+			Err: &ErrFieldTooLong{
+				Field: field,
+				Len:   len(value),
+				Max:   max,
+			},
+		})
+	}
+}
+
+func (v *validator) maxCount(field, desc string, n, max int) {
+	if n > max {
+		v.errs = append(v.errs, &FieldError{
+			Field:   field,
+			Rule:    RuleMaxLength,
+			Actual:  n,
+			Limit:   max,
+			Message: fmt.Sprintf("too many %s (count: %d max: %d)", desc, n, max),
+			Err: &ErrFieldTooLong{
+				Field: field,
+				Len:   n,
+				Max:   max,
+			},
+		})
+	}
+}
+
+func (v *validator) formatf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, &FieldError{
+		Field:   field,
+		Rule:    RuleFormat,
+		Message: fmt.Sprintf(format, args...),
+		Err:     ErrMalformedField,
+	})
+}
+
+// url checks validity of the given URL.
+func (v *validator) url(field, desc, u string, max int) {
+	v.maxLength(field, desc, u, max)
+	if len(u) == 0 {
+		return
+	}
+
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		v.formatf(field, "%s is malformed: %s", desc, err)
+		return
+	}
+	if parsedURL.Scheme != "https" {
+		v.formatf(field, "%s must use the https scheme (scheme: %s)", desc, parsedURL.Scheme)
+		return
+	}
+	if port := parsedURL.Port(); port != "" {
+		v.formatf(field, "%s must use the default port (port: %s)", desc, port)
+		return
+	}
+	if len(parsedURL.RawQuery) != 0 || len(parsedURL.Fragment) != 0 {
+		v.formatf(field, "%s must not contain query values or fragments", desc)
+	}
+}
+
+// email checks validity of the given e-mail address.
+func (v *validator) email(field, desc, email string, max int) {
+	v.maxLength(field, desc, email, max)
+	if len(email) == 0 {
+		return
+	}
+
+	parsedEmail, err := mail.ParseAddress(email)
+	if err != nil {
+		v.formatf(field, "%s is malformed: %s", desc, err)
+		return
+	}
+	if len(parsedEmail.Name) != 0 {
+		v.formatf(field, "%s must not contain a name", desc)
+	}
+}
+
+// sentryAddress checks validity of the given sentry address in the <pubkey>@<host>:<port> or
+// <host>:<port> form.
+func (v *validator) sentryAddress(field, desc, addr string, max int) {
+	v.maxLength(field, desc, addr, max)
+
+	hostPort := addr
+	if rawPk, hp, ok := strings.Cut(addr, "@"); ok {
+		var pk signature.PublicKey
+		if err := pk.UnmarshalText([]byte(rawPk)); err != nil {
+			v.formatf(field, "%s has a malformed public key: %s", desc, err)
+			return
+		}
+		hostPort = hp
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		v.formatf(field, "%s is malformed: %s", desc, err)
+		return
+	}
+	if len(host) == 0 {
+		v.formatf(field, "%s must contain a host", desc)
+		return
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		v.formatf(field, "%s has an invalid port (port: %s)", desc, port)
+	}
+}
+
+// handle checks validity of the given handle.
+func (v *validator) handle(field, desc, handle string, max int, re *regexp.Regexp) {
+	v.maxLength(field, desc, handle, max)
+	if len(handle) > 0 && !re.MatchString(handle) {
+		v.formatf(field, "%s is malformed", desc)
+	}
+}